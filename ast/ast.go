@@ -19,14 +19,19 @@ type Visitor interface {
 	VisitIfStatement(node *IfStatement) interface{}
 	VisitLogicalExpr(node *LogicalExpr) interface{}
 	VisitWhileStatement(node *WhileStatement) interface{}
+	VisitTryStatement(node *TryStatement) interface{}
 	VisitCallExpr(node *CallExpr) interface{}
 	VisitFuncDeclaration(node *FuncDeclaration) interface{}
 	VisitReturnStatement(node *ReturnStatement) interface{}
 	VisitClassDeclaration(node *ClassDeclaration) interface{}
+	VisitTraitDeclaration(node *TraitDeclaration) interface{}
 	VisitGetExpr(node *GetExpr) interface{}
 	VisitSetExpr(node *SetExpr) interface{}
 	VisitThisExpr(node *ThisExpr) interface{}
 	VisitSuperExpr(node *SuperExpr) interface{}
+	VisitInterpolationExpr(node *InterpolationExpr) interface{}
+	VisitConditionalExpr(node *ConditionalExpr) interface{}
+	VisitIndexExpr(node *IndexExpr) interface{}
 }
 
 type Node interface {
@@ -94,7 +99,10 @@ func (node *PrintStatement) Accept(v Visitor) interface{} {
 
 type VarDeclaration struct {
 	Name lexer.Token
+	Type *TypeAnnotation
 	Expr Node
+	// Const is set for const declarations, which can't be assigned
+	Const bool
 }
 
 func (node *VarDeclaration) Accept(v Visitor) interface{} {
@@ -112,6 +120,9 @@ func (node *Program) Accept(v Visitor) interface{} {
 type Assignment struct {
 	Name lexer.Token
 	Expr Node
+	// Operator is the binary operator of a compound assignment like +=,
+	// nil for =
+	Operator *lexer.Token
 }
 
 func (node *Assignment) Accept(v Visitor) interface{} {
@@ -155,22 +166,56 @@ func (node *WhileStatement) Accept(v Visitor) interface{} {
 	return v.VisitWhileStatement(node)
 }
 
+// TryStatement runs Body and, when a runtime error stops it, Catch with
+// Name bound to the message of the error.
+type TryStatement struct {
+	Body  *BlockStatement
+	Name  lexer.Token
+	Catch *BlockStatement
+}
+
+func (node *TryStatement) Accept(v Visitor) interface{} {
+	return v.VisitTryStatement(node)
+}
+
 type CallExpr struct {
 	Callee    Node
 	Arguments []Node
 
 	// ? For Location
 	Paren lexer.Token
+	// ChainEnd is set on the last link of a chain with a ?., see GetExpr
+	ChainEnd bool
 }
 
 func (node *CallExpr) Accept(v Visitor) interface{} {
 	return v.VisitCallExpr(node)
 }
 
+// Kinds of FuncDeclaration, all but FunctionKind only appear in classes
+const (
+	// FunctionKind is a function or an instance method
+	FunctionKind = iota
+	// ClassMethodKind is a method declared with class and called on the
+	// class itself
+	ClassMethodKind
+	// GetterKind is a method without parameter list that runs when its
+	// property is read
+	GetterKind
+	// SetterKind is a method declared with set that runs when its property
+	// is assigned
+	SetterKind
+)
+
 type FuncDeclaration struct {
-	Name   lexer.Token
-	Params []lexer.Token
-	Body   Node
+	Name       lexer.Token
+	Params     []lexer.Token
+	ParamTypes []*TypeAnnotation
+	ReturnType *TypeAnnotation
+	Body       Node
+	Kind       int
+	// Doc is the text of the /// comment before the declaration
+	Doc string
 }
 
 func (f *FuncDeclaration) Accept(v Visitor) interface{} {
@@ -188,17 +233,40 @@ func (node *ReturnStatement) Accept(v Visitor) interface{} {
 
 type ClassDeclaration struct {
 	Name       lexer.Token
+	Fields     []*FieldDeclaration
 	Methods    []*FuncDeclaration
 	SuperClass *Variable
+	// Traits are the traits named after with, in order
+	Traits []*Variable
+	// Doc is the text of the /// comment before the declaration
+	Doc string
 }
 
 func (node *ClassDeclaration) Accept(v Visitor) interface{} {
 	return v.VisitClassDeclaration(node)
 }
 
+// TraitDeclaration declares methods that classes mix in with
+// `class A < B with T`.
+type TraitDeclaration struct {
+	Name    lexer.Token
+	Methods []*FuncDeclaration
+	// Doc is the text of the /// comment before the declaration
+	Doc string
+}
+
+func (node *TraitDeclaration) Accept(v Visitor) interface{} {
+	return v.VisitTraitDeclaration(node)
+}
+
 type GetExpr struct {
 	Expr Node
 	Name lexer.Token
+	// Optional is set for obj?.name, which is nil when obj is nil
+	Optional bool
+	// ChainEnd is set on the last property access, call or index of a
+	// chain with a ?., the whole chain is nil when a ?. finds nil
+	ChainEnd bool
 }
 
 func (node *GetExpr) Accept(v Visitor) interface{} {
@@ -209,6 +277,9 @@ type SetExpr struct {
 	Expr  Node
 	Name  lexer.Token
 	Value Node
+	// Operator is the binary operator of a compound assignment like +=,
+	// nil for =
+	Operator *lexer.Token
 }
 
 func (node *SetExpr) Accept(v Visitor) interface{} {
@@ -231,3 +302,53 @@ type SuperExpr struct {
 func (node *SuperExpr) Accept(v Visitor) interface{} {
 	return v.VisitSuperExpr(node)
 }
+
+// InterpolationExpr is an interpolated string like "a ${b} c", its parts are
+// the string literals and the interpolated expressions in order.
+type InterpolationExpr struct {
+	Parts []Node
+}
+
+func (node *InterpolationExpr) Accept(v Visitor) interface{} {
+	return v.VisitInterpolationExpr(node)
+}
+
+// ConditionalExpr is cond ? then : else.
+type ConditionalExpr struct {
+	Condition Node
+	Then      Node
+	Else      Node
+}
+
+func (node *ConditionalExpr) Accept(v Visitor) interface{} {
+	return v.VisitConditionalExpr(node)
+}
+
+// IndexExpr is expr[index], Bracket is the closing bracket.
+type IndexExpr struct {
+	Expr    Node
+	Index   Node
+	Bracket lexer.Token
+	// ChainEnd is set on the last link of a chain with a ?., see GetExpr
+	ChainEnd bool
+}
+
+func (node *IndexExpr) Accept(v Visitor) interface{} {
+	return v.VisitIndexExpr(node)
+}
+
+// TypeAnnotation is an optional static type written after ':'. It is only
+// consumed by the type checker, the interpreter ignores it.
+type TypeAnnotation struct {
+	Name lexer.Token
+}
+
+// FieldDeclaration declares a field in a class body, e.g. `x: number = 0;`.
+// The type and the initializer are optional, a const field can only be
+// assigned once.
+type FieldDeclaration struct {
+	Name        lexer.Token
+	Type        *TypeAnnotation
+	Initializer Node
+	Const       bool
+}
@@ -12,10 +12,10 @@ import (
 func TestAstVisitor(t *testing.T) {
 	var node = BinaryExpr{
 		Left: &UnaryExpr{
-			lexer.Token{lexer.MINUS, "-", nil, 1},
+			lexer.Token{Type: lexer.MINUS, Lexeme: "-", Line: 1},
 			&LiteralExpr{123},
 		},
-		Operator: lexer.Token{lexer.STAR, "*", nil, 1},
+		Operator: lexer.Token{Type: lexer.STAR, Lexeme: "*", Line: 1},
 		Right: &GroupExpr{
 			&LiteralExpr{45.67},
 		},
@@ -12,12 +12,29 @@ type Parser struct {
 	Tokens  []lexer.Token
 	current int
 	Errors  []error
+
+	// Lines records the source line each statement starts on
+	Lines map[Node]int
+	// Branches records the line of every if, loop condition and logical
+	// operator
+	Branches map[Node]int
+	// Columns records the column of the statements and branches
+	Columns map[Node]int
+}
+
+// Position is a location in the source.
+type Position struct {
+	Line   int
+	Column int
 }
 
 func NewParser(tokens []lexer.Token) *Parser {
 	return &Parser{
-		current: 0,
-		Tokens:  tokens,
+		current:  0,
+		Tokens:   tokens,
+		Lines:    make(map[Node]int),
+		Branches: make(map[Node]int),
+		Columns:  make(map[Node]int),
 	}
 }
 
@@ -35,6 +52,11 @@ func (parser *Parser) Parse() Node {
 }
 
 func (parser *Parser) Statement() Node {
+	start := parser.Position()
+	return parser.Mark(parser.statement(), start)
+}
+
+func (parser *Parser) statement() Node {
 	if parser.Match(lexer.PRINT) {
 		return parser.PrintStatement()
 	}
@@ -50,6 +72,9 @@ func (parser *Parser) Statement() Node {
 	if parser.Match(lexer.FOR) {
 		return parser.ForStatement()
 	}
+	if parser.Match(lexer.TRY) {
+		return parser.TryStatement()
+	}
 	if parser.Match(lexer.RETURN) {
 		return parser.ReturnStatement()
 	}
@@ -73,16 +98,18 @@ func (parser *Parser) ForStatement() Node {
 	parser.MustConsume(lexer.LEFT_PAREN, utils.EXPECT_LEFT_PAREN_AFTER_FOR)
 
 	var initializer Node
-	if parser.Match(lexer.VAR) {
-		initializer = parser.VarDeclaration()
+	initializerStart := parser.Position()
+	if parser.Match(lexer.VAR, lexer.LET, lexer.CONST) {
+		initializer = parser.Mark(parser.VarDeclaration(), initializerStart)
 
 	} else if parser.Match(lexer.SEMICOLON) {
 		initializer = nil
 	} else {
-		initializer = parser.ExprStatement()
+		initializer = parser.Mark(parser.ExprStatement(), initializerStart)
 	}
 
 	var condition Node
+	conditionStart := parser.Position()
 	if parser.Match(lexer.SEMICOLON) {
 		condition = &LiteralExpr{
 			Value: true,
@@ -96,7 +123,8 @@ func (parser *Parser) ForStatement() Node {
 	if parser.Match(lexer.RIGHT_PAREN) {
 		increment = nil
 	} else {
-		increment = parser.Expression()
+		start := parser.Position()
+		increment = parser.Mark(parser.Expression(), start)
 		parser.MustConsume(lexer.RIGHT_PAREN, utils.EXPECT_RIGHT_PAREN_AFTER_CLAUSES)
 	}
 
@@ -107,10 +135,10 @@ func (parser *Parser) ForStatement() Node {
 		}
 	}
 
-	body = &WhileStatement{
+	body = parser.Branch(&WhileStatement{
 		Expr: condition,
 		Then: body,
-	}
+	}, conditionStart)
 
 	if initializer != nil {
 		body = &BlockStatement{
@@ -123,19 +151,37 @@ func (parser *Parser) ForStatement() Node {
 
 func (parser *Parser) WhileStatement() Node {
 	parser.MustConsume(lexer.LEFT_PAREN, utils.EXPECT_LEFT_PAREN_AFTER_WHILE)
+	start := parser.Position()
 	expr := parser.Expression()
 	parser.MustConsume(lexer.RIGHT_PAREN, utils.EXPECT_RIGHT_PAREN_AFTER_CONDITION)
 
 	statement := parser.Statement()
 
-	return &WhileStatement{
+	return parser.Branch(&WhileStatement{
 		Expr: expr,
 		Then: statement,
+	}, start)
+}
+
+func (parser *Parser) TryStatement() Node {
+	parser.MustConsume(lexer.LEFT_BRACE, utils.EXPECT_LEFT_BRACE_AFTER_TRY)
+	body := parser.BlockStatement().(*BlockStatement)
+	parser.MustConsume(lexer.CATCH, utils.EXPECT_CATCH_AFTER_TRY)
+	parser.MustConsume(lexer.LEFT_PAREN, utils.EXPECT_LEFT_PAREN_AFTER_CATCH)
+	name := parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_CATCH_VARIABLE_NAME)
+	parser.MustConsume(lexer.RIGHT_PAREN, utils.EXPECT_RIGHT_PAREN_AFTER_CATCH_VARIABLE)
+	parser.MustConsume(lexer.LEFT_BRACE, utils.EXPECT_LEFT_BRACE_AFTER_CATCH)
+	catch := parser.BlockStatement().(*BlockStatement)
+	return &TryStatement{
+		Body:  body,
+		Name:  name,
+		Catch: catch,
 	}
 }
 
 func (parser *Parser) IfStatement() Node {
 	parser.MustConsume(lexer.LEFT_PAREN, utils.EXPECT_LEFT_PAREN_AFTER_IF)
+	start := parser.Position()
 	expr := parser.Expression()
 	parser.MustConsume(lexer.RIGHT_PAREN, utils.EXPECT_RIGHT_PAREN_AFTER_IF_CONDITION)
 	thenStatement := parser.Statement()
@@ -144,22 +190,56 @@ func (parser *Parser) IfStatement() Node {
 	if parser.Match(lexer.ELSE) {
 		elseStatement = parser.Statement()
 	}
-	return &IfStatement{
+	return parser.Branch(&IfStatement{
 		Expr: expr,
 		Then: thenStatement,
 		Else: elseStatement,
+	}, start)
+}
+
+// Conditional parses cond ? then : else, which is right associative and
+// binds looser than ??.
+func (parser *Parser) Conditional() Node {
+	node := parser.Coalesce()
+	if parser.Match(lexer.QUESTION) {
+		question := parser.Previous()
+		thenExpr := parser.Expression()
+		parser.MustConsume(lexer.COLON, utils.EXPECT_COLON_IN_CONDITIONAL)
+		elseExpr := parser.Conditional()
+		node = parser.Branch(&ConditionalExpr{
+			Condition: node,
+			Then:      thenExpr,
+			Else:      elseExpr,
+		}, Position{Line: question.Line, Column: question.Column})
+	}
+	return node
+}
+
+// Coalesce parses a ?? b, which is a when a isn't nil and b otherwise.
+func (parser *Parser) Coalesce() Node {
+	node := parser.LogicOr()
+	for !parser.isAtEnd() && parser.Match(lexer.QUESTION_QUESTION) {
+		operator := parser.Previous()
+		right := parser.LogicOr()
+		node = parser.Branch(&LogicalExpr{
+			Left:     node,
+			Right:    right,
+			Operator: operator,
+		}, Position{Line: operator.Line, Column: operator.Column})
 	}
+	return node
 }
 
 func (parser *Parser) LogicOr() Node {
 	node := parser.LogicAnd()
 	for !parser.isAtEnd() && parser.Match(lexer.OR) {
+		operator := parser.Previous()
 		right := parser.LogicAnd()
-		node = &LogicalExpr{
+		node = parser.Branch(&LogicalExpr{
 			Left:     node,
 			Right:    right,
-			Operator: parser.Previous(),
-		}
+			Operator: operator,
+		}, Position{Line: operator.Line, Column: operator.Column})
 	}
 	return node
 }
@@ -167,12 +247,13 @@ func (parser *Parser) LogicOr() Node {
 func (parser *Parser) LogicAnd() Node {
 	node := parser.Equality()
 	for !parser.isAtEnd() && parser.Match(lexer.AND) {
+		operator := parser.Previous()
 		right := parser.Equality()
-		node = &LogicalExpr{
+		node = parser.Branch(&LogicalExpr{
 			Left:     node,
 			Right:    right,
-			Operator: parser.Previous(),
-		}
+			Operator: operator,
+		}, Position{Line: operator.Line, Column: operator.Column})
 	}
 	return node
 }
@@ -215,22 +296,37 @@ func (parser *Parser) Declaration() Node {
 
 	}()
 
-	if parser.Match(lexer.VAR) {
-		return parser.VarDeclaration()
+	start := parser.Position()
+
+	if parser.Match(lexer.VAR, lexer.LET, lexer.CONST) {
+		return parser.Mark(parser.VarDeclaration(), start)
 	}
 
 	if parser.Match(lexer.FUN) {
-		return parser.FuncDeclaration()
+		doc := parser.Previous().Doc
+		function := parser.FuncDeclaration()
+		function.Doc = doc
+		return parser.Mark(function, start)
 	}
 
 	if parser.Match(lexer.CLASS) {
-		return parser.ClassDeclaration()
+		doc := parser.Previous().Doc
+		class := parser.ClassDeclaration()
+		class.Doc = doc
+		return parser.Mark(class, start)
+	}
+
+	if parser.Match(lexer.TRAIT) {
+		doc := parser.Previous().Doc
+		trait := parser.TraitDeclaration()
+		trait.Doc = doc
+		return parser.Mark(trait, start)
 	}
 
 	return parser.Statement()
 }
 
-func (parser *Parser) ClassDeclaration() Node {
+func (parser *Parser) ClassDeclaration() *ClassDeclaration {
 	name := parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_CLASS_NAME)
 	var superClass *Variable
 
@@ -241,57 +337,199 @@ func (parser *Parser) ClassDeclaration() Node {
 		}
 	}
 
+	var traits []*Variable
+	if parser.Match(lexer.WITH) {
+		for {
+			token := parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_TRAIT_NAME)
+			traits = append(traits, &Variable{
+				Name: token,
+			})
+			if !parser.Match(lexer.COMMA) {
+				break
+			}
+		}
+	}
+
 	parser.MustConsume(lexer.LEFT_BRACE, utils.EXPECT_LEFT_BRACE_BEFORE_CLASS_BODY)
 
+	var fields []*FieldDeclaration
 	var methods []*FuncDeclaration
 	for !parser.isAtEnd() && !parser.Check(lexer.RIGHT_BRACE) {
-		method := parser.FuncDeclaration()
+		if parser.Match(lexer.CONST) {
+			field := parser.FieldDeclaration()
+			field.Const = true
+			fields = append(fields, field)
+			continue
+		}
+		if (parser.Check(lexer.IDENTIFIER) || parser.Check(lexer.PRIVATE_IDENTIFIER)) &&
+			(parser.CheckNext(lexer.COLON) || parser.CheckNext(lexer.EQUAL) || parser.CheckNext(lexer.SEMICOLON)) {
+			fields = append(fields, parser.FieldDeclaration())
+			continue
+		}
+		doc := parser.Peek().Doc
+		method := parser.Method()
+		method.Doc = doc
 		methods = append(methods, method)
 	}
 	parser.MustConsume(lexer.RIGHT_BRACE, utils.EXPECT_RIGHT_BRACE_AFTER_CLASS_BODY)
 	return &ClassDeclaration{
 		Name:       name,
+		Fields:     fields,
 		Methods:    methods,
 		SuperClass: superClass,
+		Traits:     traits,
+	}
+}
+
+// TraitDeclaration parses a trait, whose body only has methods.
+func (parser *Parser) TraitDeclaration() *TraitDeclaration {
+	name := parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_TRAIT_NAME)
+	parser.MustConsume(lexer.LEFT_BRACE, utils.EXPECT_LEFT_BRACE_BEFORE_TRAIT_BODY)
+
+	var methods []*FuncDeclaration
+	for !parser.isAtEnd() && !parser.Check(lexer.RIGHT_BRACE) {
+		doc := parser.Peek().Doc
+		method := parser.FuncDeclaration()
+		method.Doc = doc
+		methods = append(methods, method)
 	}
+	parser.MustConsume(lexer.RIGHT_BRACE, utils.EXPECT_RIGHT_BRACE_AFTER_TRAIT_BODY)
+	return &TraitDeclaration{
+		Name:    name,
+		Methods: methods,
+	}
+}
+
+func (parser *Parser) FieldDeclaration() *FieldDeclaration {
+	name := parser.MemberName(utils.EXPECT_FIELD_NAME)
+	var fieldType *TypeAnnotation
+	if parser.Match(lexer.COLON) {
+		fieldType = parser.TypeAnnotation()
+	}
+	var initializer Node
+	if parser.Match(lexer.EQUAL) {
+		initializer = parser.Expression()
+	}
+	parser.MustConsume(lexer.SEMICOLON, utils.EXPECT_SEMICOLON_AFTER_FIELD_DECLARATION)
+	return &FieldDeclaration{
+		Name:        name,
+		Type:        fieldType,
+		Initializer: initializer,
+	}
+}
+
+// MemberName consumes the name of a class member or property, which may be
+// a private #name.
+func (parser *Parser) MemberName(message string) lexer.Token {
+	if parser.Match(lexer.PRIVATE_IDENTIFIER) {
+		return parser.Previous()
+	}
+	return parser.MustConsume(lexer.IDENTIFIER, message)
+}
+
+func (parser *Parser) TypeAnnotation() *TypeAnnotation {
+	if parser.Match(lexer.NIL) {
+		return &TypeAnnotation{
+			Name: parser.Previous(),
+		}
+	}
+	name := parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_TYPE_NAME)
+	return &TypeAnnotation{
+		Name: name,
+	}
+}
+
+// Method parses a method in a class body. A class method starts with class,
+// a getter has no parameter list and a setter starts with set.
+func (parser *Parser) Method() *FuncDeclaration {
+	if parser.Match(lexer.CLASS) {
+		method := parser.FuncDeclaration()
+		method.Kind = ClassMethodKind
+		return method
+	}
+	if parser.Check(lexer.IDENTIFIER) && parser.Peek().Lexeme == "set" && parser.CheckNext(lexer.IDENTIFIER) {
+		parser.Advance()
+		setter := parser.FuncDeclaration()
+		setter.Kind = SetterKind
+		if len(setter.Params) != 1 {
+			parser.ErrorAt(setter.Name, utils.SETTER_PARAMETER)
+		}
+		return setter
+	}
+	if parser.Check(lexer.IDENTIFIER) && parser.CheckNext(lexer.LEFT_BRACE) {
+		name := parser.Advance()
+		return &FuncDeclaration{
+			Name: name,
+			Body: parser.Statement(),
+			Kind: GetterKind,
+		}
+	}
+	return parser.Function(parser.MemberName(utils.EXPECT_FUNCTION_NAME))
 }
 
 func (parser *Parser) FuncDeclaration() *FuncDeclaration {
-	name := parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_FUNCTION_NAME)
+	return parser.Function(parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_FUNCTION_NAME))
+}
+
+// Function parses the parameters and the body of the function name.
+func (parser *Parser) Function(name lexer.Token) *FuncDeclaration {
 	parser.MustConsume(lexer.LEFT_PAREN, utils.EXPECT_LEFT_PAREN_AFTER_FUNCTION_NAME)
 
 	var parameters []lexer.Token
+	var paramTypes []*TypeAnnotation
 
-	for !parser.isAtEnd() && !parser.Check(lexer.RIGHT_PAREN) {
+	if !parser.isAtEnd() && !parser.Check(lexer.RIGHT_PAREN) {
 		for {
 			param := parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_PARAM_NAME)
 			parameters = append(parameters, param)
+			var paramType *TypeAnnotation
+			if parser.Match(lexer.COLON) {
+				paramType = parser.TypeAnnotation()
+			}
+			paramTypes = append(paramTypes, paramType)
 			if !parser.Match(lexer.COMMA) {
 				break
 			}
 		}
 	}
 	parser.MustConsume(lexer.RIGHT_PAREN, utils.EXPECT_RIGHT_PAREN_AFTER_PARAMETERS)
+
+	var returnType *TypeAnnotation
+	if parser.Match(lexer.COLON) {
+		returnType = parser.TypeAnnotation()
+	}
 	body := parser.Statement()
 
 	return &FuncDeclaration{
-		Name:   name,
-		Params: parameters,
-		Body:   body,
+		Name:       name,
+		Params:     parameters,
+		ParamTypes: paramTypes,
+		ReturnType: returnType,
+		Body:       body,
 	}
 }
 
+// VarDeclaration parses the rest of a var, let or const declaration, only
+// const requires an initializer.
 func (parser *Parser) VarDeclaration() Node {
-
+	isConst := parser.Previous().Type == lexer.CONST
 	name := parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_VARIABLE_NAME)
+	var varType *TypeAnnotation
+	if parser.Match(lexer.COLON) {
+		varType = parser.TypeAnnotation()
+	}
 	var initializer Node
 	if parser.Match(lexer.EQUAL) {
 		initializer = parser.Expression()
+	} else if isConst {
+		parser.Error(utils.CONST_WITHOUT_INITIALIZER)
 	}
 	parser.MustConsume(lexer.SEMICOLON, utils.EXPECT_SEMICOLON_AFTER_VARIABLE_DECLARATION)
 	return &VarDeclaration{
-		Name: name,
-		Expr: initializer,
+		Name:  name,
+		Type:  varType,
+		Expr:  initializer,
+		Const: isConst,
 	}
 }
 
@@ -313,35 +551,50 @@ func (parser *Parser) Equality() Node {
 	return node
 }
 
+// compoundOperators maps compound assignments to their binary operator.
+var compoundOperators = map[int]int{
+	lexer.PLUS_EQUAL:  lexer.PLUS,
+	lexer.MINUS_EQUAL: lexer.MINUS,
+	lexer.STAR_EQUAL:  lexer.STAR,
+	lexer.SLASH_EQUAL: lexer.SLASH,
+}
+
 func (parser *Parser) Assignment() Node {
-	expr := parser.LogicOr()
-	if parser.Match(lexer.EQUAL) {
+	expr := parser.Conditional()
+	if parser.Match(lexer.EQUAL, lexer.PLUS_EQUAL, lexer.MINUS_EQUAL, lexer.STAR_EQUAL, lexer.SLASH_EQUAL) {
+		equals := parser.Previous()
+		var operator *lexer.Token
+		if binary, ok := compoundOperators[equals.Type]; ok {
+			token := equals
+			token.Type = binary
+			operator = &token
+		}
 		value := parser.Assignment()
 		if v, ok := expr.(*Variable); ok {
 			return &Assignment{
-				Name: v.Name,
-				Expr: value,
+				Name:     v.Name,
+				Expr:     value,
+				Operator: operator,
 			}
 		} else if v, ok := expr.(*GetExpr); ok {
 			return &SetExpr{
-				Expr:  v.Expr,
-				Name:  v.Name,
-				Value: value,
+				Expr:     v.Expr,
+				Name:     v.Name,
+				Value:    value,
+				Operator: operator,
 			}
 		}
 
-		err := errors.New(utils.INVALID_ASSIGNMENT_TARGET)
-		fmt.Println(err)
-		panic(err)
+		parser.ErrorAt(equals, utils.INVALID_ASSIGNMENT_TARGET)
 	}
 	return expr
 }
 
 func (parser *Parser) Comparison() Node {
-	node := parser.Term()
+	node := parser.BitOr()
 	for parser.Match(lexer.GREATER, lexer.GREATER_EQUAL, lexer.LESS, lexer.LESS_EQUAL) {
 		token := parser.Previous()
-		right := parser.Term()
+		right := parser.BitOr()
 		node = &BinaryExpr{
 			Left:     node,
 			Operator: token,
@@ -352,6 +605,40 @@ func (parser *Parser) Comparison() Node {
 	return node
 }
 
+// Binary parses a left associative binary expression whose operands are
+// parsed by operand.
+func (parser *Parser) Binary(operand func() Node, types ...int) Node {
+	node := operand()
+	for parser.Match(types...) {
+		token := parser.Previous()
+		right := operand()
+		node = &BinaryExpr{
+			Left:     node,
+			Operator: token,
+			Right:    right,
+		}
+	}
+	return node
+}
+
+// The bitwise operators bind tighter than comparisons, so a & 1 == 0 tests
+// the lowest bit.
+func (parser *Parser) BitOr() Node {
+	return parser.Binary(parser.BitXor, lexer.PIPE)
+}
+
+func (parser *Parser) BitXor() Node {
+	return parser.Binary(parser.BitAnd, lexer.CARET)
+}
+
+func (parser *Parser) BitAnd() Node {
+	return parser.Binary(parser.Shift, lexer.AMPERSAND)
+}
+
+func (parser *Parser) Shift() Node {
+	return parser.Binary(parser.Term, lexer.LESS_LESS, lexer.GREATER_GREATER)
+}
+
 func (parser *Parser) Term() Node {
 	node := parser.Factor()
 	for parser.Match(lexer.MINUS, lexer.PLUS) {
@@ -368,7 +655,7 @@ func (parser *Parser) Term() Node {
 
 func (parser *Parser) Factor() Node {
 	node := parser.Unary()
-	for parser.Match(lexer.SLASH, lexer.STAR) {
+	for parser.Match(lexer.SLASH, lexer.STAR, lexer.PERCENT, lexer.TILDE_SLASH) {
 		token := parser.Previous()
 		right := parser.Unary()
 		node = &BinaryExpr{
@@ -381,7 +668,7 @@ func (parser *Parser) Factor() Node {
 }
 
 func (parser *Parser) Unary() Node {
-	if parser.Match(lexer.BANG, lexer.MINUS) {
+	if parser.Match(lexer.BANG, lexer.MINUS, lexer.TILDE) {
 		token := parser.Previous()
 		node := parser.Unary()
 		return &UnaryExpr{
@@ -389,14 +676,38 @@ func (parser *Parser) Unary() Node {
 			Right:    node,
 		}
 	}
-	return parser.Call()
+	return parser.Power()
+}
+
+// Power parses the right associative **, which binds tighter than a unary
+// operator on its left, -2 ** 2 is -4, but not on its right, 2 ** -1 is 0.5.
+func (parser *Parser) Power() Node {
+	node := parser.Call()
+	if parser.Match(lexer.STAR_STAR) {
+		token := parser.Previous()
+		right := parser.Unary()
+		return &BinaryExpr{
+			Left:     node,
+			Operator: token,
+			Right:    right,
+		}
+	}
+	return node
 }
 
 func (parser *Parser) Call() Node {
 	expr := parser.Primary()
 
-	for parser.Match(lexer.LEFT_PAREN) || parser.Match(lexer.DOT) {
-		if parser.Previous().Type == lexer.LEFT_PAREN {
+	chained := false
+	for parser.Match(lexer.LEFT_PAREN, lexer.DOT, lexer.QUESTION_DOT, lexer.LEFT_BRACKET) {
+		if parser.Previous().Type == lexer.LEFT_BRACKET {
+			index := parser.Expression()
+			expr = &IndexExpr{
+				Expr:    expr,
+				Index:   index,
+				Bracket: parser.MustConsume(lexer.RIGHT_BRACKET, utils.EXPECT_RIGHT_BRACKET_AFTER_INDEX),
+			}
+		} else if parser.Previous().Type == lexer.LEFT_PAREN {
 			var arguments []Node
 			for !parser.Check(lexer.RIGHT_PAREN) && !parser.isAtEnd() {
 				arg := parser.Expression()
@@ -407,7 +718,7 @@ func (parser *Parser) Call() Node {
 			}
 			parser.MustConsume(lexer.RIGHT_PAREN, utils.EXPECT_RIGHT_PAREN_AFTER_ARGUMENTS)
 			if len(arguments) > 255 {
-				utils.Error(parser.Previous().Line, utils.WARN_NO_MORE_THAN_MAXIMUM_ARGUMENTS)
+				parser.ErrorAt(parser.Previous(), utils.WARN_NO_MORE_THAN_MAXIMUM_ARGUMENTS)
 			}
 			expr = &CallExpr{
 				Callee:    expr,
@@ -415,15 +726,28 @@ func (parser *Parser) Call() Node {
 				Paren:     parser.Previous(),
 			}
 		} else {
-			name := parser.MustConsume(lexer.IDENTIFIER, utils.EXPECT_PROPERTY_NAME_AFTER_DOT)
+			optional := parser.Previous().Type == lexer.QUESTION_DOT
+			chained = chained || optional
+			name := parser.MemberName(utils.EXPECT_PROPERTY_NAME_AFTER_DOT)
 			expr = &GetExpr{
-				Expr: expr,
-				Name: name,
+				Expr:     expr,
+				Name:     name,
+				Optional: optional,
 			}
 		}
 
 	}
 
+	if chained {
+		switch link := expr.(type) {
+		case *GetExpr:
+			link.ChainEnd = true
+		case *CallExpr:
+			link.ChainEnd = true
+		case *IndexExpr:
+			link.ChainEnd = true
+		}
+	}
 	return expr
 }
 
@@ -462,6 +786,9 @@ func (parser *Parser) Primary() Node {
 			Value: parser.Previous().Value,
 		}
 	}
+	if parser.Match(lexer.INTERPOLATION) {
+		return parser.Interpolation()
+	}
 	if parser.Match(lexer.THIS) {
 		return &ThisExpr{
 			Keyword: parser.Previous(),
@@ -482,20 +809,58 @@ func (parser *Parser) Primary() Node {
 			Method:  token,
 		}
 	}
+	parser.Error(utils.EXPECT_EXPRESSION)
 	return nil
 }
 
+// ParseExpression parses the tokens as a single expression, it is used by
+// tools that evaluate user input such as the debugger.
+func (parser *Parser) ParseExpression() (node Node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	node = parser.Expression()
+	if !parser.isAtEnd() {
+		parser.Error(utils.EXPECT_END_OF_EXPRESSION)
+	}
+	return node, nil
+}
+
 func (parser *Parser) MustConsume(tokenType int, message string) lexer.Token {
 	if parser.Check(tokenType) {
 		parser.current++
 		return parser.Previous()
 	}
 
-	message = fmt.Sprintf("[line %d] Error %s", parser.Previous().Line, message)
-	err := errors.New(message)
+	parser.Error(message)
+	return lexer.Token{}
+}
+
+// Error reports an error at the next token and unwinds to the enclosing
+// declaration, which synchronizes.
+func (parser *Parser) Error(message string) {
+	if parser.isAtEnd() {
+		panic(parser.report(parser.CurrentLine(), " at end", message))
+	}
+	panic(parser.ErrorAt(parser.Peek(), message))
+}
+
+// ErrorAt reports an error at token without unwinding.
+func (parser *Parser) ErrorAt(token lexer.Token, message string) error {
+	return parser.report(token.Line, fmt.Sprintf(" at '%s'", token.Lexeme), message)
+}
+
+func (parser *Parser) report(line int, where string, message string) error {
+	err := errors.New(utils.FormatError(line, where, message))
 	parser.Errors = append(parser.Errors, err)
-	fmt.Println(err)
-	panic(err)
+	utils.Report(err)
+	return err
 }
 
 func (parser *Parser) Match(tokenTypes ...int) bool {
@@ -515,6 +880,47 @@ func (parser *Parser) Check(tokenType int) bool {
 	return parser.Peek().Type == tokenType
 }
 
+func (parser *Parser) CheckNext(tokenType int) bool {
+	if parser.current+1 >= len(parser.Tokens) {
+		return false
+	}
+	return parser.Tokens[parser.current+1].Type == tokenType
+}
+
+// CurrentLine is the line of the next token, used to locate statements
+func (parser *Parser) CurrentLine() int {
+	if !parser.isAtEnd() {
+		return parser.Peek().Line
+	}
+	if parser.current > 0 {
+		return parser.Previous().Line
+	}
+	return 0
+}
+
+// Position is the position of the next token
+func (parser *Parser) Position() Position {
+	if !parser.isAtEnd() {
+		token := parser.Peek()
+		return Position{Line: token.Line, Column: token.Column}
+	}
+	return Position{Line: parser.CurrentLine()}
+}
+
+func (parser *Parser) Mark(node Node, start Position) Node {
+	if node != nil {
+		parser.Lines[node] = start.Line
+		parser.Columns[node] = start.Column
+	}
+	return node
+}
+
+func (parser *Parser) Branch(node Node, start Position) Node {
+	parser.Branches[node] = start.Line
+	parser.Columns[node] = start.Column
+	return node
+}
+
 func (parser *Parser) isAtEnd() bool {
 	return parser.current >= len(parser.Tokens)
 }
@@ -540,11 +946,15 @@ func (parser *Parser) Synchronize() {
 	for !parser.isAtEnd() {
 		switch parser.Peek().Type {
 		case lexer.CLASS,
+			lexer.TRAIT,
 			lexer.FUN,
 			lexer.VAR,
+			lexer.LET,
+			lexer.CONST,
 			lexer.FOR,
 			lexer.IF,
 			lexer.WHILE,
+			lexer.TRY,
 			lexer.PRINT,
 			lexer.RETURN:
 			return
@@ -556,3 +966,25 @@ func (parser *Parser) Synchronize() {
 		parser.Advance()
 	}
 }
+
+// Interpolation parses the rest of an interpolated string, the lexer emits
+// an INTERPOLATION token before every expression and a STRING at the end.
+func (parser *Parser) Interpolation() Node {
+	node := &InterpolationExpr{}
+	part := parser.Previous()
+	for {
+		if part.Value != "" {
+			node.Parts = append(node.Parts, &LiteralExpr{Value: part.Value})
+		}
+		node.Parts = append(node.Parts, parser.Expression())
+		if !parser.Match(lexer.INTERPOLATION) {
+			break
+		}
+		part = parser.Previous()
+	}
+	end := parser.MustConsume(lexer.STRING, utils.EXPECT_RIGHT_BRACE_AFTER_INTERPOLATION)
+	if end.Value != "" {
+		node.Parts = append(node.Parts, &LiteralExpr{Value: end.Value})
+	}
+	return node
+}
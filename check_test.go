@@ -0,0 +1,38 @@
+package glox_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/utils"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestCheckFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var diagnostics bytes.Buffer
+	output := utils.Output
+	utils.Output = &diagnostics
+	defer func() {
+		utils.Output = output
+	}()
+
+	good := filepath.Join(dir, "good.lox")
+	ioutil.WriteFile(good, []byte(`var a: number = 1;`), 0644)
+	bad := filepath.Join(dir, "bad.lox")
+	ioutil.WriteFile(bad, []byte(`var a: number = "one";`), 0644)
+
+	g := &glox.Glox{}
+	assert.Equal(t, g.CheckFile(good), nil)
+	assert.Equal(t, g.CheckFile(bad), glox.ErrCompile)
+	err = g.CheckFile(filepath.Join(dir, "missing.lox"))
+	assert.Equal(t, os.IsNotExist(err), true)
+}
@@ -1,20 +1,329 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"regexp"
 
 	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/coverage"
+	"github.com/jameslahm/glox/dap"
+	"github.com/jameslahm/glox/debugger"
+	"github.com/jameslahm/glox/doc"
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/profiler"
+	"github.com/jameslahm/glox/testrunner"
+	"github.com/jameslahm/glox/utils"
+	"github.com/jameslahm/glox/visitor"
 )
 
+func usage() {
+	fmt.Println("Usage: glox [--profile] [--profile-output file] [--coverprofile file] [script [arg...]]")
+	fmt.Println("       glox check script...")
+	fmt.Println("       glox cover [--html file] profile...")
+	fmt.Println("       glox doc [--html file] script...")
+	fmt.Println("       glox test [-run regexp] [-v] [-coverprofile file] [path...]")
+	fmt.Println("       glox debug script [arg...]")
+	fmt.Println("       glox dap [--listen address]")
+}
+
 func main() {
-	args := os.Args
-	var g = &glox.Glox{}
-	if len(args) > 2 {
-		fmt.Println("Usage: glox [script]")
-	} else if len(args) == 2 {
-		g.RunFile(args[1])
-	} else {
+	args := os.Args[1:]
+	var g = &glox.Glox{FileSystem: true}
+
+	if len(args) > 0 {
+		switch {
+		case args[0] == "check":
+			exit(check(g, args[1:]))
+			return
+		case len(args) >= 2 && args[0] == "debug":
+			g.Args = args[2:]
+			script, err := g.CompileFile(args[1])
+			if err != nil {
+				exit(err)
+			}
+			cli := debugger.NewCLI(args[1], script.Source, g.StdinReader(), os.Stdout)
+			err = cli.Run(g.NewInterpreter(script), script.Node, script.Lines)
+			if err != debugger.ErrQuit {
+				exit(err)
+			}
+			return
+		case args[0] == "cover":
+			if err := cover(args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		case args[0] == "doc":
+			if err := document(g, args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		case args[0] == "test":
+			passed, err := test(g, args[1:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if !passed {
+				os.Exit(1)
+			}
+			return
+		case args[0] == "dap":
+			if err := serveDap(g, args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	flags := flag.NewFlagSet("glox", flag.ExitOnError)
+	flags.Usage = usage
+	profile := flags.Bool("profile", false, "profile the script")
+	profileOutput := flags.String("profile-output", "glox.pprof", "write the pprof profile to `file`")
+	coverProfile := flags.String("coverprofile", "", "write a coverage profile to `file`")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
 		g.RunPrompt()
+		return
+	}
+	// Everything after the script belongs to the script
+	g.Args = flags.Args()[1:]
+	if *profile || *coverProfile != "" {
+		exit(runInstrumented(g, flags.Arg(0), *profile, *profileOutput, *coverProfile))
+		return
+	}
+	exit(g.RunFile(flags.Arg(0)))
+}
+
+// runInstrumented runs a script under the profiler, which prints its totals
+// to stderr and writes a pprof profile, and the coverage recorder.
+func runInstrumented(g *glox.Glox, path string, profile bool, profileOutput string, coverProfile string) error {
+	script, err := g.CompileFile(path)
+	if err != nil {
+		return err
+	}
+	interpreter := g.NewInterpreter(script)
+	var p *profiler.Profiler
+	if profile {
+		p = profiler.NewProfiler(path, script.Lines)
+		interpreter.AddHook(p)
+	}
+	var c *coverage.Coverage
+	if coverProfile != "" {
+		c = coverage.NewCoverage(path, script.Lines, script.Branches, script.Columns)
+		interpreter.AddHook(c)
+	}
+
+	if p != nil {
+		p.Start()
+	}
+	runErr := interpreter.Interpret(script.Node)
+	if _, ok := runErr.(*visitor.Exit); runErr != nil && !ok {
+		fmt.Fprintln(utils.Output, runErr)
+	}
+	if p != nil {
+		p.Stop()
+		p.WriteTable(os.Stderr, script.Source)
+		if err := writeFile(profileOutput, p.WritePprof); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "\nProfile written to %s, view it with go tool pprof %s\n", profileOutput, profileOutput)
+	}
+	if c != nil {
+		if err := writeFile(coverProfile, c.Profile().Write); err != nil {
+			return err
+		}
+	}
+	return runErr
+}
+
+// exit ends the process following the Lox conventions, with status 65
+// after compile errors and 70 after runtime errors, which have already been
+// reported, or with the status the script passed to exit.
+func exit(err error) {
+	if err == nil {
+		return
+	}
+	if e, ok := err.(*visitor.Exit); ok {
+		os.Exit(e.Code)
+	}
+	if err == glox.ErrCompile {
+		os.Exit(65)
+	}
+	if _, ok := err.(*glox_error.RuntimeError); ok {
+		os.Exit(70)
+	}
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// check type checks scripts, the errors of every one of them are reported.
+func check(g *glox.Glox, args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("glox check: no script given")
+	}
+
+	var failed error
+	for _, path := range flags.Args() {
+		err := g.CheckFile(path)
+		if err == glox.ErrCompile {
+			failed = err
+		} else if err != nil {
+			return err
+		}
+	}
+	return failed
+}
+
+// cover merges coverage profiles and reports them as text on stdout or as
+// an HTML page.
+func cover(args []string) error {
+	flags := flag.NewFlagSet("cover", flag.ExitOnError)
+	output := flags.String("html", "", "write an HTML report to `file`")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("glox cover: no profile given")
+	}
+
+	profile := coverage.NewProfile()
+	for _, path := range flags.Args() {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		p, err := coverage.ReadProfile(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		profile.Merge(p)
+	}
+	reports, err := coverage.Report(profile)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		coverage.WriteText(os.Stdout, reports)
+		return nil
+	}
+	return writeFile(*output, func(w io.Writer) error {
+		return coverage.WriteHTML(w, reports)
+	})
+}
+
+// document writes the API documentation of Lox files as Markdown on stdout
+// or as an HTML page.
+func document(g *glox.Glox, args []string) error {
+	flags := flag.NewFlagSet("doc", flag.ExitOnError)
+	output := flags.String("html", "", "write an HTML page to `file`")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("glox doc: no script given")
+	}
+
+	var files []*doc.File
+	for _, path := range flags.Args() {
+		script, err := g.CompileFile(path)
+		if err == glox.ErrCompile {
+			return fmt.Errorf("glox doc: %s has errors", path)
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, doc.Extract(path, script.Node))
+	}
+	if *output == "" {
+		doc.WriteMarkdown(os.Stdout, files)
+		return nil
+	}
+	return writeFile(*output, func(w io.Writer) error {
+		return doc.WriteHTML(w, files)
+	})
+}
+
+// test runs the tests of the _test.lox files found in the given paths, the
+// current directory by default.
+func test(g *glox.Glox, args []string) (bool, error) {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	run := flags.String("run", "", "run only the tests matching `regexp`")
+	verbose := flags.Bool("v", false, "report every test")
+	coverProfile := flags.String("coverprofile", "", "write a coverage profile of the tests to `file`")
+	flags.Parse(args)
+
+	runner := testrunner.NewRunner(g, os.Stdout)
+	runner.Verbose = *verbose
+	if *run != "" {
+		pattern, err := regexp.Compile(*run)
+		if err != nil {
+			return false, err
+		}
+		runner.Run = pattern
+	}
+	if *coverProfile != "" {
+		runner.Coverage = coverage.NewProfile()
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := testrunner.Discover(paths)
+	if err != nil {
+		return false, err
+	}
+	if len(files) == 0 {
+		return false, fmt.Errorf("glox test: no test files found")
+	}
+	passed := runner.RunFiles(files)
+	if *coverProfile != "" {
+		if err := writeFile(*coverProfile, runner.Coverage.Write); err != nil {
+			return false, err
+		}
+	}
+	return passed, nil
+}
+
+// serveDap runs a debug adapter over stdio, or for a single client
+// connecting to the listen address.
+func serveDap(g *glox.Glox, args []string) error {
+	flags := flag.NewFlagSet("dap", flag.ExitOnError)
+	listen := flags.String("listen", "", "serve a client connecting to `address` instead of stdio")
+	flags.Parse(args)
+
+	if *listen == "" {
+		return dap.NewServer(g, os.Stdin, os.Stdout).Serve()
+	}
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	fmt.Fprintf(os.Stderr, "DAP server listening at %s\n", listener.Addr())
+	conn, err := listener.Accept()
+	if err != nil {
+		return err
 	}
+	defer conn.Close()
+	return dap.NewServer(g, conn, conn).Serve()
 }
@@ -0,0 +1,143 @@
+package glox_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/utils"
+	"github.com/jameslahm/glox/visitor"
+)
+
+// The conformance corpus follows the conventions of the craftinginterpreters
+// test suite. Every .lox file below testdata/conformance is run and its
+// output compared with the annotations in its comments:
+//
+//	print 1; // expect: 1
+//	nil();   // expect runtime error: Can only call functions and classes
+//	print;   // Error at ';': Expect expression
+//	// [line 3] Error at end: Expect '}' after block
+var (
+	expectOutput       = regexp.MustCompile(`// expect: ?(.*)$`)
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)$`)
+	expectError        = regexp.MustCompile(`// (Error.*)$`)
+	expectLineError    = regexp.MustCompile(`// \[line (\d+)\] (Error.*)$`)
+)
+
+const conformanceDir = "testdata/conformance"
+
+var conformanceTime = time.Date(2024, time.March, 10, 14, 30, 15, 250*int(time.Millisecond), time.UTC)
+
+type expectations struct {
+	output       []string
+	errors       []string
+	runtimeError string
+}
+
+func parseExpectations(source string) expectations {
+	var e expectations
+	for i, line := range strings.Split(source, "\n") {
+		number := i + 1
+		if match := expectOutput.FindStringSubmatch(line); match != nil {
+			e.output = append(e.output, match[1])
+		} else if match := expectRuntimeError.FindStringSubmatch(line); match != nil {
+			e.runtimeError = fmt.Sprintf("%s\n[line %d]", match[1], number)
+		} else if match := expectLineError.FindStringSubmatch(line); match != nil {
+			e.errors = append(e.errors, fmt.Sprintf("[line %s] %s", match[1], match[2]))
+		} else if match := expectError.FindStringSubmatch(line); match != nil {
+			e.errors = append(e.errors, fmt.Sprintf("[line %d] %s", number, match[1]))
+		}
+	}
+	return e
+}
+
+func lines(output string) []string {
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+func runConformance(t *testing.T, path string) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := parseExpectations(string(source))
+
+	var stdout, diagnostics bytes.Buffer
+	output := utils.Output
+	utils.Output = &diagnostics
+	defer func() {
+		utils.Output = output
+	}()
+	// Scripts see a clock stopped at conformanceTime in UTC
+	g := &glox.Glox{Stdout: &stdout, Time: visitor.NewManualTime(conformanceTime)}
+	err = g.Run(string(source))
+
+	switch {
+	case len(expected.errors) > 0:
+		if err != glox.ErrCompile {
+			t.Errorf("expected compile errors, got %v", err)
+		}
+		compareLines(t, "diagnostics", expected.errors, lines(diagnostics.String()))
+	case expected.runtimeError != "":
+		if err == nil {
+			t.Errorf("expected a runtime error")
+		}
+		if diagnostics.String() != expected.runtimeError+"\n" {
+			t.Errorf("expected runtime error:\n%s\ngot:\n%s", expected.runtimeError, diagnostics.String())
+		}
+	default:
+		if err != nil || diagnostics.Len() != 0 {
+			t.Errorf("unexpected error %v:\n%s", err, diagnostics.String())
+		}
+	}
+	compareLines(t, "output", expected.output, lines(stdout.String()))
+}
+
+func compareLines(t *testing.T, name string, expected []string, actual []string) {
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			t.Errorf("missing %s line %d: %q", name, i+1, expected[i])
+		case i >= len(expected):
+			t.Errorf("unexpected %s line %d: %q", name, i+1, actual[i])
+		case expected[i] != actual[i]:
+			t.Errorf("%s line %d: expected %q, got %q", name, i+1, expected[i], actual[i])
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	var files []string
+	err := filepath.Walk(conformanceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".lox") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no conformance tests found")
+	}
+	for _, path := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(path), conformanceDir+"/"), ".lox")
+		t.Run(name, func(t *testing.T) {
+			runConformance(t, path)
+		})
+	}
+}
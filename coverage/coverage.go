@@ -0,0 +1,79 @@
+package coverage
+
+import (
+	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/environment"
+)
+
+// Coverage is an interpreter hook that counts how often every statement of a
+// script is executed and how often every branch condition was true or false.
+type Coverage struct {
+	Path       string
+	statements map[ast.Node]*Block
+	branches   map[ast.Node]*Block
+}
+
+// NewCoverage creates a block for every statement and branch the parser
+// recorded, so code that never runs shows up in the profile too.
+func NewCoverage(path string, lines map[ast.Node]int, branches map[ast.Node]int, columns map[ast.Node]int) *Coverage {
+	c := &Coverage{
+		Path:       path,
+		statements: make(map[ast.Node]*Block),
+		branches:   make(map[ast.Node]*Block),
+	}
+	for node, line := range lines {
+		// Blocks only group statements
+		if _, ok := node.(*ast.BlockStatement); ok {
+			continue
+		}
+		c.statements[node] = &Block{Path: path, Line: line, Column: columns[node], Kind: KindStatement}
+	}
+	for node, line := range branches {
+		c.branches[node] = &Block{Path: path, Line: line, Column: columns[node], Kind: branchKind(node)}
+	}
+	return c
+}
+
+func branchKind(node ast.Node) string {
+	switch node := node.(type) {
+	case *ast.IfStatement:
+		return KindIf
+	case *ast.WhileStatement:
+		return KindWhile
+	case *ast.ConditionalExpr:
+		return KindConditional
+	case *ast.LogicalExpr:
+		return node.Operator.Lexeme
+	}
+	return ""
+}
+
+func (c *Coverage) BeforeStatement(node ast.Node, env *environment.Env) {
+	if block, ok := c.statements[node]; ok {
+		block.Count++
+	}
+}
+
+func (c *Coverage) Branch(node ast.Node, taken bool) {
+	block, ok := c.branches[node]
+	if !ok {
+		return
+	}
+	if taken {
+		block.True++
+	} else {
+		block.False++
+	}
+}
+
+// Profile returns the counts recorded so far.
+func (c *Coverage) Profile() *Profile {
+	profile := NewProfile()
+	for _, block := range c.statements {
+		profile.Add(*block)
+	}
+	for _, block := range c.branches {
+		profile.Add(*block)
+	}
+	return profile
+}
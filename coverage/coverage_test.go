@@ -0,0 +1,61 @@
+package coverage_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/coverage"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+const source = `fun sign(n) {
+  if (n < 0) {
+    return -1;
+  }
+  return 1;
+}
+var both = sign(1) > 0 and true;
+while (false) print "never";
+`
+
+func TestCoverage(t *testing.T) {
+	g := &glox.Glox{}
+	script, err := g.Compile(source)
+	assert.Equal(t, err, nil)
+	interpreter := g.NewInterpreter(script)
+	interpreter.Stdout = ioutil.Discard
+	c := coverage.NewCoverage("sign.lox", script.Lines, script.Branches, script.Columns)
+	interpreter.AddHook(c)
+	script.Node.Accept(interpreter)
+
+	// A written profile reads back the same, and reading it twice adds up
+	// the counts
+	var buf bytes.Buffer
+	assert.Equal(t, c.Profile().Write(&buf), nil)
+	profile, err := coverage.ReadProfile(strings.NewReader(buf.String()))
+	assert.Equal(t, err, nil)
+	var again bytes.Buffer
+	assert.Equal(t, profile.Write(&again), nil)
+	assert.Equal(t, again.String(), buf.String())
+	other, err := coverage.ReadProfile(strings.NewReader(buf.String()))
+	assert.Equal(t, err, nil)
+	profile.Merge(other)
+
+	report := coverage.FileReportOf("sign.lox", source, profile)
+	assert.Equal(t, report.Statements, 7)
+	assert.Equal(t, report.Covered, 5)
+	assert.Equal(t, report.Outcomes, 6)
+	assert.Equal(t, report.CoveredOutcomes, 3)
+	assert.Equal(t, report.Lines[1].Status(), "partial")
+	assert.Equal(t, report.Lines[2].Status(), "uncovered")
+	assert.Equal(t, report.Lines[3].Status(), "none")
+	assert.Equal(t, report.Lines[4].Count, 2)
+
+	var text bytes.Buffer
+	coverage.WriteText(&text, []*coverage.FileReport{report})
+	assert.Equal(t, strings.Contains(text.String(), "#####:    3:     return -1;"), true)
+	assert.Equal(t, strings.Contains(text.String(), "[and true 2 false 0]"), true)
+}
@@ -0,0 +1,189 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Logical operators are branches of the kind of their lexeme, like "and".
+const (
+	KindStatement   = "stmt"
+	KindIf          = "if"
+	KindWhile       = "while"
+	KindConditional = "?:"
+)
+
+const profileHeader = "mode: count"
+
+// Block is a statement or a branch of a file. Statements count how often
+// they were executed, branches how often their condition was true and false.
+type Block struct {
+	Path   string
+	Line   int
+	Column int
+	Kind   string
+	Count  int
+	True   int
+	False  int
+}
+
+func (b *Block) IsBranch() bool {
+	return b.Kind != KindStatement
+}
+
+type blockKey struct {
+	path   string
+	line   int
+	column int
+	kind   string
+}
+
+// Profile holds the blocks of any number of files. Adding a block that is
+// already known adds up the counts, so the profiles of several runs can be
+// merged.
+type Profile struct {
+	blocks map[blockKey]*Block
+}
+
+func NewProfile() *Profile {
+	return &Profile{blocks: make(map[blockKey]*Block)}
+}
+
+func (p *Profile) Add(block Block) {
+	key := blockKey{block.Path, block.Line, block.Column, block.Kind}
+	if known, ok := p.blocks[key]; ok {
+		known.Count += block.Count
+		known.True += block.True
+		known.False += block.False
+		return
+	}
+	p.blocks[key] = &block
+}
+
+func (p *Profile) Merge(other *Profile) {
+	for _, block := range other.blocks {
+		p.Add(*block)
+	}
+}
+
+// Blocks returns the blocks ordered by file and position.
+func (p *Profile) Blocks() []*Block {
+	var blocks []*Block
+	for _, block := range p.blocks {
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		a, b := blocks[i], blocks[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return a.Kind < b.Kind
+	})
+	return blocks
+}
+
+// Files returns the sorted paths of the files in the profile.
+func (p *Profile) Files() []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, block := range p.blocks {
+		if !seen[block.Path] {
+			seen[block.Path] = true
+			files = append(files, block.Path)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Write writes the profile with one tab separated block per line:
+//
+//	path	line:column	stmt	count
+//	path	line:column	kind	true	false
+func (p *Profile) Write(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintln(writer, profileHeader)
+	for _, block := range p.Blocks() {
+		if block.IsBranch() {
+			fmt.Fprintf(writer, "%s\t%d:%d\t%s\t%d\t%d\n", block.Path, block.Line, block.Column, block.Kind, block.True, block.False)
+		} else {
+			fmt.Fprintf(writer, "%s\t%d:%d\t%s\t%d\n", block.Path, block.Line, block.Column, block.Kind, block.Count)
+		}
+	}
+	return writer.Flush()
+}
+
+// ReadProfile parses a profile written by Write.
+func ReadProfile(r io.Reader) (*Profile, error) {
+	profile := NewProfile()
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if lineNumber == 1 {
+			if line != profileHeader {
+				return nil, fmt.Errorf("line 1: expected %q", profileHeader)
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		block, err := parseBlock(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		profile.Add(block)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNumber == 0 {
+		return nil, fmt.Errorf("empty profile")
+	}
+	return profile, nil
+}
+
+func parseBlock(line string) (Block, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 4 {
+		return Block{}, fmt.Errorf("malformed block %q", line)
+	}
+	block := Block{Path: fields[0], Kind: fields[2]}
+	position := strings.Split(fields[1], ":")
+	if len(position) != 2 {
+		return Block{}, fmt.Errorf("malformed position %q", fields[1])
+	}
+	var counts []int
+	for _, field := range append(position, fields[3:]...) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return Block{}, fmt.Errorf("malformed number %q", field)
+		}
+		counts = append(counts, n)
+	}
+	block.Line, block.Column = counts[0], counts[1]
+	if block.IsBranch() {
+		if len(counts) != 4 {
+			return Block{}, fmt.Errorf("malformed branch %q", line)
+		}
+		block.True, block.False = counts[2], counts[3]
+	} else {
+		if len(counts) != 3 {
+			return Block{}, fmt.Errorf("malformed statement %q", line)
+		}
+		block.Count = counts[2]
+	}
+	return block, nil
+}
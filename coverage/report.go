@@ -0,0 +1,197 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// LineReport is the coverage of one source line.
+type LineReport struct {
+	Number int
+	Source string
+	// Statements is the number of statements starting on the line and
+	// Covered how many of them were executed
+	Statements int
+	Covered    int
+	// Count is the highest execution count of the statements
+	Count    int
+	Branches []*Block
+}
+
+// Status is "none" for lines without statements and otherwise one of
+// "covered", "partial" or "uncovered".
+func (l *LineReport) Status() string {
+	switch {
+	case l.Statements == 0 && len(l.Branches) == 0:
+		return "none"
+	case l.Covered == 0 && l.Statements > 0:
+		return "uncovered"
+	case l.Covered < l.Statements:
+		return "partial"
+	}
+	for _, branch := range l.Branches {
+		if branch.True == 0 || branch.False == 0 {
+			return "partial"
+		}
+	}
+	return "covered"
+}
+
+// FileReport is the coverage of one file. Every branch has two outcomes,
+// the condition being true and being false.
+type FileReport struct {
+	Path            string
+	Lines           []*LineReport
+	Statements      int
+	Covered         int
+	Outcomes        int
+	CoveredOutcomes int
+}
+
+func percent(covered int, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(covered) * 100 / float64(total)
+}
+
+func (f *FileReport) StatementPercent() float64 {
+	return percent(f.Covered, f.Statements)
+}
+
+func (f *FileReport) BranchPercent() float64 {
+	return percent(f.CoveredOutcomes, f.Outcomes)
+}
+
+// Report builds the report of every file in the profile, reading the
+// sources from disk.
+func Report(profile *Profile) ([]*FileReport, error) {
+	var reports []*FileReport
+	for _, path := range profile.Files() {
+		source, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, FileReportOf(path, string(source), profile))
+	}
+	return reports, nil
+}
+
+// FileReportOf builds the report of the file at path with the given source.
+func FileReportOf(path string, source string, profile *Profile) *FileReport {
+	report := &FileReport{Path: path}
+	for i, text := range strings.Split(strings.TrimSuffix(source, "\n"), "\n") {
+		report.Lines = append(report.Lines, &LineReport{Number: i + 1, Source: text})
+	}
+	for _, block := range profile.Blocks() {
+		if block.Path != path || block.Line < 1 {
+			continue
+		}
+		for block.Line > len(report.Lines) {
+			report.Lines = append(report.Lines, &LineReport{Number: len(report.Lines) + 1})
+		}
+		line := report.Lines[block.Line-1]
+		if block.IsBranch() {
+			line.Branches = append(line.Branches, block)
+			report.Outcomes += 2
+			if block.True > 0 {
+				report.CoveredOutcomes++
+			}
+			if block.False > 0 {
+				report.CoveredOutcomes++
+			}
+			continue
+		}
+		line.Statements++
+		report.Statements++
+		if block.Count > 0 {
+			line.Covered++
+			report.Covered++
+		}
+		if block.Count > line.Count {
+			line.Count = block.Count
+		}
+	}
+	return report
+}
+
+// WriteText writes the reports in the style of gcov: every line is prefixed
+// with its execution count, "#####" marks lines that never ran and "*"
+// lines that only partly ran.
+func WriteText(w io.Writer, reports []*FileReport) {
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s: statements %d/%d (%.1f%%), branches %d/%d (%.1f%%)\n",
+			report.Path, report.Covered, report.Statements, report.StatementPercent(),
+			report.CoveredOutcomes, report.Outcomes, report.BranchPercent())
+		for _, line := range report.Lines {
+			count := "-"
+			switch line.Status() {
+			case "uncovered":
+				count = "#####"
+			case "partial":
+				count = fmt.Sprintf("%d*", line.Count)
+			case "covered":
+				count = fmt.Sprint(line.Count)
+			}
+			fmt.Fprintf(w, "%9s:%5d: %s%s\n", count, line.Number, line.Source, branchSummary(line))
+		}
+	}
+}
+
+func branchSummary(line *LineReport) string {
+	var summary []string
+	for _, branch := range line.Branches {
+		summary = append(summary, fmt.Sprintf("%s true %d false %d", branch.Kind, branch.True, branch.False))
+	}
+	if len(summary) == 0 {
+		return ""
+	}
+	return "  [" + strings.Join(summary, ", ") + "]"
+}
+
+var htmlTemplate = template.Must(template.New("coverage").Funcs(template.FuncMap{
+	"branches": branchSummary,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>glox coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { line-height: 1.3; }
+.line { display: block; }
+.number, .count { display: inline-block; text-align: right; color: #888; }
+.number { width: 4em; }
+.count { width: 5em; margin-right: 1em; }
+.covered { background: #dfd; }
+.partial { background: #ffd; }
+.uncovered { background: #fdd; }
+.branch { color: #888; }
+</style>
+</head>
+<body>
+{{range .}}
+<h2 id="{{.Path}}">{{.Path}}</h2>
+<p>Statements {{.Covered}}/{{.Statements}} ({{printf "%.1f" .StatementPercent}}%),
+branches {{.CoveredOutcomes}}/{{.Outcomes}} ({{printf "%.1f" .BranchPercent}}%)</p>
+<pre>
+{{- range .Lines}}
+<span class="line {{.Status}}"><span class="number">{{.Number}}</span><span class="count">{{if ne .Status "none"}}{{.Count}}{{end}}</span>{{.Source}}<span class="branch">{{branches .}}</span></span>
+{{- end}}
+</pre>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the reports as a single page with the lines colored by
+// their coverage.
+func WriteHTML(w io.Writer, reports []*FileReport) error {
+	return htmlTemplate.Execute(w, reports)
+}
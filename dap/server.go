@@ -0,0 +1,487 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/debugger"
+	"github.com/jameslahm/glox/environment"
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/utils"
+	"github.com/jameslahm/glox/visitor"
+)
+
+// The interpreter is single threaded, every request refers to this thread.
+const threadID = 1
+
+// Server is a debug adapter for one Lox program. Requests are handled on the
+// goroutine calling Serve while the program runs on its own goroutine, which
+// blocks in Stopped whenever the debugger stops it.
+type Server struct {
+	Glox *glox.Glox
+
+	in  *bufio.Reader
+	out io.Writer
+
+	mu  sync.Mutex
+	seq int
+
+	path        string
+	script      *glox.Script
+	interpreter *visitor.AstInterpreter
+	debugger    *debugger.Debugger
+	breakpoints []int
+	stopOnEntry bool
+	started     bool
+
+	// stderr carries diagnostics as output events while a program is
+	// launched, output is the utils.Output it replaced until the session ends.
+	stderr io.Writer
+	output io.Writer
+
+	resume  chan struct{}
+	stopped bool
+	frames  []debugger.Frame
+	refs    map[int]interface{}
+}
+
+func NewServer(g *glox.Glox, in io.Reader, out io.Writer) *Server {
+	return &Server{
+		Glox:   g,
+		in:     bufio.NewReader(in),
+		out:    out,
+		resume: make(chan struct{}),
+		refs:   make(map[int]interface{}),
+	}
+}
+
+// Serve handles requests until the client disconnects.
+func (s *Server) Serve() error {
+	defer s.restoreOutput()
+	for {
+		body, err := ReadMessage(s.in)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		var request Request
+		if err := json.Unmarshal(body, &request); err != nil {
+			return err
+		}
+		if done := s.Handle(&request); done {
+			return nil
+		}
+	}
+}
+
+func (s *Server) send(message interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	switch m := message.(type) {
+	case *Response:
+		m.Seq = s.seq
+	case *Event:
+		m.Seq = s.seq
+	}
+	WriteMessage(s.out, message)
+}
+
+func (s *Server) Respond(request *Request, body interface{}) {
+	s.send(&Response{
+		Type:       "response",
+		RequestSeq: request.Seq,
+		Success:    true,
+		Command:    request.Command,
+		Body:       body,
+	})
+}
+
+func (s *Server) RespondError(request *Request, message string) {
+	s.send(&Response{
+		Type:       "response",
+		RequestSeq: request.Seq,
+		Success:    false,
+		Command:    request.Command,
+		Message:    message,
+	})
+}
+
+func (s *Server) SendEvent(event string, body interface{}) {
+	s.send(&Event{
+		Type:  "event",
+		Event: event,
+		Body:  body,
+	})
+}
+
+// Handle dispatches one request, it returns true once the session is over.
+func (s *Server) Handle(request *Request) bool {
+	switch request.Command {
+	case "initialize":
+		s.Respond(request, map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+		})
+	case "launch":
+		s.Launch(request)
+	case "setBreakpoints":
+		s.SetBreakpoints(request)
+	case "setExceptionBreakpoints":
+		s.Respond(request, map[string]interface{}{"breakpoints": []Breakpoint{}})
+	case "configurationDone":
+		s.Respond(request, nil)
+		s.Start()
+	case "threads":
+		s.Respond(request, map[string]interface{}{
+			"threads": []Thread{{ID: threadID, Name: "main"}},
+		})
+	case "stackTrace":
+		s.StackTrace(request)
+	case "scopes":
+		s.Scopes(request)
+	case "variables":
+		s.Variables(request)
+	case "evaluate":
+		s.Evaluate(request)
+	case "continue":
+		s.Resume(request, (*debugger.Debugger).Continue, map[string]interface{}{"allThreadsContinued": true})
+	case "next":
+		s.Resume(request, (*debugger.Debugger).Next, nil)
+	case "stepIn":
+		s.Resume(request, (*debugger.Debugger).Step, nil)
+	case "stepOut":
+		s.Resume(request, (*debugger.Debugger).Finish, nil)
+	case "pause":
+		if s.debugger != nil {
+			s.debugger.Pause()
+		}
+		s.Respond(request, nil)
+	case "disconnect", "terminate":
+		s.restoreOutput()
+		s.Respond(request, nil)
+		return true
+	default:
+		s.RespondError(request, fmt.Sprintf("Unsupported request %s", request.Command))
+	}
+	return false
+}
+
+func (s *Server) Launch(request *Request) {
+	var args LaunchArguments
+	if err := json.Unmarshal(request.Arguments, &args); err != nil || args.Program == "" {
+		s.RespondError(request, "Launch needs a program")
+		return
+	}
+
+	// Diagnostics and program output travel as output events, the adapter
+	// may be talking over stdout.
+	if s.stderr == nil {
+		s.stderr = &outputWriter{server: s, category: "stderr"}
+	}
+	if utils.Output != s.stderr {
+		s.output = utils.Output
+		utils.Output = s.stderr
+	}
+	script, err := s.Glox.CompileFile(args.Program)
+	if err != nil {
+		s.RespondError(request, fmt.Sprintf("Can't compile %s: %v", args.Program, err))
+		return
+	}
+
+	s.path = args.Program
+	s.script = script
+	s.stopOnEntry = args.StopOnEntry
+	s.interpreter = s.Glox.NewInterpreter(script)
+	s.interpreter.Stdout = &outputWriter{server: s, category: "stdout"}
+	// The adapter may be reading the protocol from stdin, the program gets
+	// no input rather than the client's messages
+	s.interpreter.Stdin = bufio.NewReader(strings.NewReader(""))
+	s.debugger = debugger.NewDebugger(s.interpreter, script.Lines, s)
+	for _, line := range s.breakpoints {
+		s.debugger.SetBreakpoint(line)
+	}
+
+	s.Respond(request, nil)
+	s.SendEvent("initialized", nil)
+}
+
+func (s *Server) SetBreakpoints(request *Request) {
+	var args SetBreakpointsArguments
+	if err := json.Unmarshal(request.Arguments, &args); err != nil {
+		s.RespondError(request, err.Error())
+		return
+	}
+
+	var statementLines map[int]bool
+	if s.debugger != nil {
+		statementLines = s.debugger.StatementLines()
+		s.debugger.ClearBreakpoints()
+	}
+	s.breakpoints = nil
+
+	breakpoints := []Breakpoint{}
+	for _, bp := range args.Breakpoints {
+		breakpoint := Breakpoint{Line: bp.Line, Verified: true}
+		if s.path != "" && !samePath(args.Source.Path, s.path) {
+			breakpoint.Verified = false
+			breakpoint.Message = "Only the launched program can have breakpoints"
+		} else if statementLines != nil && !statementLines[bp.Line] {
+			breakpoint.Verified = false
+			breakpoint.Message = "No statement on this line"
+		} else {
+			s.breakpoints = append(s.breakpoints, bp.Line)
+			if s.debugger != nil {
+				s.debugger.SetBreakpoint(bp.Line)
+			}
+		}
+		breakpoints = append(breakpoints, breakpoint)
+	}
+	s.Respond(request, map[string]interface{}{"breakpoints": breakpoints})
+}
+
+// restoreOutput gives utils.Output back once the session is over.
+func (s *Server) restoreOutput() {
+	if s.stderr != nil && utils.Output == s.stderr {
+		utils.Output = s.output
+	}
+}
+
+// Start runs the launched program on its own goroutine.
+func (s *Server) Start() {
+	if s.debugger == nil || s.started {
+		return
+	}
+	s.started = true
+	if s.stopOnEntry {
+		s.debugger.Step()
+	}
+	go func() {
+		exitCode := 0
+		if err := s.interpreter.Interpret(s.script.Node); err != nil {
+			if exit, ok := err.(*visitor.Exit); ok {
+				exitCode = exit.Code
+			} else {
+				exitCode = 70
+				fmt.Fprintln(s.stderr, err)
+			}
+		}
+		s.SendEvent("exited", map[string]interface{}{"exitCode": exitCode})
+		s.SendEvent("terminated", nil)
+	}()
+}
+
+// Stopped implements debugger.Frontend, it runs on the program goroutine.
+func (s *Server) Stopped(d *debugger.Debugger, reason string, line int) {
+	if s.stopOnEntry && reason == debugger.ReasonStep {
+		reason = "entry"
+		s.stopOnEntry = false
+	}
+	s.mu.Lock()
+	s.stopped = true
+	s.frames = d.StackTrace()
+	s.refs = make(map[int]interface{})
+	s.mu.Unlock()
+
+	s.SendEvent("stopped", map[string]interface{}{
+		"reason":            reason,
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	})
+	<-s.resume
+}
+
+func (s *Server) Resume(request *Request, command func(*debugger.Debugger), body interface{}) {
+	if !s.isStopped() {
+		s.RespondError(request, "Program is not stopped")
+		return
+	}
+	command(s.debugger)
+	s.mu.Lock()
+	s.stopped = false
+	s.mu.Unlock()
+	s.Respond(request, body)
+	s.resume <- struct{}{}
+}
+
+func (s *Server) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+func (s *Server) StackTrace(request *Request) {
+	if !s.isStopped() {
+		s.RespondError(request, "Program is not stopped")
+		return
+	}
+	source := Source{Name: filepath.Base(s.path), Path: s.path}
+	frames := []StackFrame{}
+	for i, frame := range s.frames {
+		frames = append(frames, StackFrame{
+			ID:     i,
+			Name:   frame.Name,
+			Source: source,
+			Line:   frame.Line,
+			Column: 1,
+		})
+	}
+	s.Respond(request, map[string]interface{}{
+		"stackFrames": frames,
+		"totalFrames": len(frames),
+	})
+}
+
+// localScope lists the environments of a frame below the globals, inner
+// environments shadow outer ones.
+type localScope []*environment.Env
+
+func (s *Server) Scopes(request *Request) {
+	var args ScopesArguments
+	json.Unmarshal(request.Arguments, &args)
+	if !s.isStopped() || args.FrameID < 0 || args.FrameID >= len(s.frames) {
+		s.RespondError(request, "Unknown frame")
+		return
+	}
+
+	var locals localScope
+	var globals *environment.Env
+	for _, scope := range s.debugger.Scopes(s.frames[args.FrameID]) {
+		if scope.Global {
+			globals = scope.Env
+		} else {
+			locals = append(locals, scope.Env)
+		}
+	}
+	scopes := []Scope{}
+	if len(locals) != 0 {
+		scopes = append(scopes, Scope{Name: "Locals", VariablesReference: s.reference(locals)})
+	}
+	if globals != nil {
+		scopes = append(scopes, Scope{Name: "Globals", VariablesReference: s.reference(globals)})
+	}
+	s.Respond(request, map[string]interface{}{"scopes": scopes})
+}
+
+func (s *Server) Variables(request *Request) {
+	var args VariablesArguments
+	json.Unmarshal(request.Arguments, &args)
+	s.mu.Lock()
+	target, ok := s.refs[args.VariablesReference]
+	s.mu.Unlock()
+	if !s.isStopped() || !ok {
+		s.RespondError(request, "Unknown variables reference")
+		return
+	}
+
+	variables := []Variable{}
+	switch t := target.(type) {
+	case localScope:
+		seen := make(map[string]bool)
+		for _, env := range t {
+			for _, name := range debugger.SortedNames(env) {
+				if !seen[name] {
+					seen[name] = true
+					variables = append(variables, s.variable(name, env.Values[name]))
+				}
+			}
+		}
+	case *environment.Env:
+		for _, name := range debugger.SortedNames(t) {
+			variables = append(variables, s.variable(name, t.Values[name]))
+		}
+	case *visitor.LoxInstance:
+		var names []string
+		for name := range t.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			variables = append(variables, s.variable(name, t.Fields[name]))
+		}
+	}
+	s.Respond(request, map[string]interface{}{"variables": variables})
+}
+
+func (s *Server) Evaluate(request *Request) {
+	var args EvaluateArguments
+	json.Unmarshal(request.Arguments, &args)
+	if !s.isStopped() || args.FrameID < 0 || args.FrameID >= len(s.frames) {
+		s.RespondError(request, "Expressions can only be evaluated while the program is stopped")
+		return
+	}
+
+	value, err := s.debugger.Evaluate(args.Expression, s.frames[args.FrameID])
+	if err != nil {
+		if runtimeError, ok := err.(*glox_error.RuntimeError); ok {
+			s.RespondError(request, runtimeError.Message())
+		} else {
+			s.RespondError(request, err.Error())
+		}
+		return
+	}
+	result := s.variable("", value)
+	s.Respond(request, map[string]interface{}{
+		"result":             result.Value,
+		"type":               result.Type,
+		"variablesReference": result.VariablesReference,
+	})
+}
+
+func (s *Server) variable(name string, value interface{}) Variable {
+	variable := Variable{
+		Name:  name,
+		Value: visitor.Inspect(value),
+		Type:  typeName(value),
+	}
+	if instance, ok := value.(*visitor.LoxInstance); ok {
+		variable.VariablesReference = s.reference(instance)
+	}
+	return variable
+}
+
+// reference returns an id the client uses to expand target, ids are only
+// valid until the program resumes.
+func (s *Server) reference(target interface{}) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := len(s.refs) + 1
+	s.refs[id] = target
+	return id
+}
+
+func typeName(value interface{}) string {
+	if name := visitor.TypeName(value); name != "unknown" {
+		return name
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func samePath(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// outputWriter forwards everything written to it as output events.
+type outputWriter struct {
+	server   *Server
+	category string
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.server.SendEvent("output", map[string]interface{}{
+		"category": w.category,
+		"output":   string(p),
+	})
+	return len(p), nil
+}
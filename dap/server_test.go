@@ -0,0 +1,195 @@
+package dap_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/dap"
+	"github.com/jameslahm/glox/utils"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+const program = `class Point {
+  init(x) {
+    this.x = x;
+  }
+}
+
+fun scale(p, by) {
+  var result = p.x * by;
+  return result;
+}
+
+var p = Point(2);
+print scale(p, 3);
+`
+
+type client struct {
+	t      *testing.T
+	seq    int
+	writer io.Writer
+	reader *bufio.Reader
+}
+
+func (c *client) send(command string, arguments interface{}) {
+	c.seq++
+	args, _ := json.Marshal(arguments)
+	dap.WriteMessage(c.writer, &dap.Request{
+		Seq:       c.seq,
+		Type:      "request",
+		Command:   command,
+		Arguments: args,
+	})
+}
+
+// expect reads messages until the response to command or the named event.
+func (c *client) expect(kind string, name string) map[string]interface{} {
+	for {
+		body, err := dap.ReadMessage(c.reader)
+		if err != nil {
+			c.t.Fatalf("waiting for %s %s: %v", kind, name, err)
+		}
+		var message map[string]interface{}
+		json.Unmarshal(body, &message)
+		if message["type"] == kind && (message["command"] == name || message["event"] == name) {
+			return message
+		}
+	}
+}
+
+func (c *client) body(kind string, name string) map[string]interface{} {
+	body, _ := c.expect(kind, name)["body"].(map[string]interface{})
+	return body
+}
+
+// start serves a session of g debugging source over pipes, it returns the
+// client, the program path, the result of Serve and a cleanup function.
+func start(t *testing.T, g *glox.Glox, source string) (*client, string, chan error, func()) {
+	dir, err := ioutil.TempDir("", "glox-dap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "main.lox")
+	ioutil.WriteFile(path, []byte(source), 0644)
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	server := dap.NewServer(g, serverReader, serverWriter)
+	done := make(chan error)
+	go func() { done <- server.Serve() }()
+	c := &client{t: t, writer: clientWriter, reader: bufio.NewReader(clientReader)}
+	return c, path, done, func() { os.RemoveAll(dir) }
+}
+
+func TestDebugSession(t *testing.T) {
+	c, path, done, cleanup := start(t, &glox.Glox{}, program)
+	defer cleanup()
+
+	c.send("initialize", map[string]interface{}{"adapterID": "glox"})
+	c.expect("response", "initialize")
+	c.send("launch", map[string]interface{}{"program": path})
+	c.expect("event", "initialized")
+
+	c.send("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": path},
+		"breakpoints": []map[string]interface{}{{"line": 8}, {"line": 6}},
+	})
+	breakpoints := c.body("response", "setBreakpoints")["breakpoints"].([]interface{})
+	assert.Equal(t, breakpoints[0].(map[string]interface{})["verified"], true)
+	assert.Equal(t, breakpoints[1].(map[string]interface{})["verified"], false)
+
+	c.send("configurationDone", nil)
+	stopped := c.body("event", "stopped")
+	assert.Equal(t, stopped["reason"], "breakpoint")
+
+	c.send("stackTrace", map[string]interface{}{"threadId": 1})
+	frames := c.body("response", "stackTrace")["stackFrames"].([]interface{})
+	assert.Equal(t, len(frames), 2)
+	assert.Equal(t, frames[0].(map[string]interface{})["name"], "scale")
+	assert.Equal(t, frames[0].(map[string]interface{})["line"], float64(8))
+	assert.Equal(t, frames[1].(map[string]interface{})["line"], float64(13))
+
+	c.send("scopes", map[string]interface{}{"frameId": 0})
+	scopes := c.body("response", "scopes")["scopes"].([]interface{})
+	locals := scopes[0].(map[string]interface{})
+	assert.Equal(t, locals["name"], "Locals")
+
+	c.send("variables", map[string]interface{}{"variablesReference": locals["variablesReference"]})
+	variables := c.body("response", "variables")["variables"].([]interface{})
+	assert.Equal(t, len(variables), 2)
+	point := variables[1].(map[string]interface{})
+	assert.Equal(t, point["name"], "p")
+	assert.Equal(t, point["value"], "Point instance")
+
+	c.send("variables", map[string]interface{}{"variablesReference": point["variablesReference"]})
+	fields := c.body("response", "variables")["variables"].([]interface{})
+	assert.Equal(t, fields[0].(map[string]interface{})["name"], "x")
+	assert.Equal(t, fields[0].(map[string]interface{})["value"], "2")
+
+	c.send("evaluate", map[string]interface{}{"expression": "p.x * by + 1", "frameId": 0})
+	assert.Equal(t, c.body("response", "evaluate")["result"], "7")
+
+	c.send("next", map[string]interface{}{"threadId": 1})
+	c.expect("event", "stopped")
+	c.send("stackTrace", map[string]interface{}{"threadId": 1})
+	frames = c.body("response", "stackTrace")["stackFrames"].([]interface{})
+	assert.Equal(t, frames[0].(map[string]interface{})["line"], float64(9))
+
+	c.send("continue", map[string]interface{}{"threadId": 1})
+	output := c.body("event", "output")
+	assert.Equal(t, output["output"], "6\n")
+	c.expect("event", "terminated")
+
+	c.send("disconnect", nil)
+	c.expect("response", "disconnect")
+	assert.Equal(t, <-done, nil)
+}
+
+func TestRuntimeError(t *testing.T) {
+	output := utils.Output
+	c, path, done, cleanup := start(t, &glox.Glox{}, "print 1 + nil;\n")
+	defer cleanup()
+
+	c.send("initialize", map[string]interface{}{"adapterID": "glox"})
+	c.expect("response", "initialize")
+	c.send("launch", map[string]interface{}{"program": path})
+	c.expect("event", "initialized")
+	c.send("configurationDone", nil)
+
+	stderr := c.body("event", "output")
+	assert.Equal(t, stderr["category"], "stderr")
+	assert.Equal(t, c.body("event", "exited")["exitCode"], float64(70))
+	c.expect("event", "terminated")
+
+	c.send("disconnect", nil)
+	c.expect("response", "disconnect")
+	assert.Equal(t, <-done, nil)
+	assert.Equal(t, utils.Output == output, true)
+}
+
+func TestProgramInput(t *testing.T) {
+	// Stdin of the adapter carries the protocol, the program must not read it
+	g := &glox.Glox{Stdin: strings.NewReader("Content-Length: 2\r\n\r\n{}")}
+	c, path, done, cleanup := start(t, g, "print readLine();\n")
+	defer cleanup()
+
+	c.send("initialize", map[string]interface{}{"adapterID": "glox"})
+	c.expect("response", "initialize")
+	c.send("launch", map[string]interface{}{"program": path})
+	c.expect("event", "initialized")
+	c.send("configurationDone", nil)
+
+	assert.Equal(t, c.body("event", "output")["output"], "nil\n")
+	assert.Equal(t, c.body("event", "exited")["exitCode"], float64(0))
+
+	c.send("disconnect", nil)
+	c.expect("response", "disconnect")
+	assert.Equal(t, <-done, nil)
+}
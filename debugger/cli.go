@@ -0,0 +1,225 @@
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/utils"
+	"github.com/jameslahm/glox/visitor"
+)
+
+// ErrQuit is returned by Run when the user quits before the program ends.
+var ErrQuit = errors.New("quit")
+
+// quit unwinds the interpreter from Stopped up to Run.
+type quit struct{}
+
+const cliHelp = `Commands:
+  break LINE (b)     set a breakpoint
+  delete LINE (d)    delete a breakpoint
+  info               list breakpoints
+  continue (c)       run until the next breakpoint
+  step (s)           run to the next statement, entering calls
+  next (n)           run to the next statement of the current function
+  finish (f)         run until the current function returns
+  backtrace (bt)     print the call stack
+  frame N            select frame N of the call stack
+  locals (l)         print the variables of the selected frame
+  print EXPR (p)     evaluate an expression in the selected frame
+  list               print the source around the current line
+  quit (q)           stop debugging
+`
+
+// CLI is a line oriented debugger frontend in the style of gdb.
+type CLI struct {
+	Path   string
+	Source []string
+	// In is read line by line, a script reading the standard input through
+	// the same reader gets the lines the CLI didn't consume
+	In  *bufio.Reader
+	Out io.Writer
+
+	frame int
+}
+
+func NewCLI(path string, source string, in io.Reader, out io.Writer) *CLI {
+	reader, ok := in.(*bufio.Reader)
+	if !ok {
+		reader = bufio.NewReader(in)
+	}
+	return &CLI{
+		Path:   path,
+		Source: strings.Split(source, "\n"),
+		In:     reader,
+		Out:    out,
+	}
+}
+
+// Run executes node under the debugger, stopping before the first statement
+// so breakpoints can be set. It returns the error that stopped the program,
+// as Interpret does, or ErrQuit.
+func (c *CLI) Run(interpreter *visitor.AstInterpreter, node ast.Node, lines map[ast.Node]int) (err error) {
+	d := NewDebugger(interpreter, lines, c)
+	d.Step()
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(quit); !ok {
+				panic(r)
+			}
+			err = ErrQuit
+		}
+	}()
+
+	err = interpreter.Interpret(node)
+	switch e := err.(type) {
+	case nil:
+		fmt.Fprintln(c.Out, "Program finished")
+	case *visitor.Exit:
+		fmt.Fprintf(c.Out, "Program exited with status %d\n", e.Code)
+	default:
+		fmt.Fprintln(utils.Output, err)
+	}
+	return err
+}
+
+func (c *CLI) Stopped(d *Debugger, reason string, line int) {
+	c.frame = 0
+	if reason == ReasonBreakpoint {
+		fmt.Fprintf(c.Out, "Breakpoint at %s:%d\n", c.Path, line)
+	}
+	c.PrintLine(line)
+
+	for {
+		fmt.Fprint(c.Out, "(glox) ")
+		input, err := c.In.ReadString('\n')
+		if err != nil && input == "" {
+			// Input is closed, let the program run to its end
+			d.ClearBreakpoints()
+			d.Continue()
+			return
+		}
+		fields := strings.Fields(input)
+		if len(fields) == 0 {
+			continue
+		}
+		command, args := fields[0], fields[1:]
+
+		switch command {
+		case "c", "continue":
+			d.Continue()
+			return
+		case "s", "step":
+			d.Step()
+			return
+		case "n", "next":
+			d.Next()
+			return
+		case "f", "finish":
+			d.Finish()
+			return
+		case "b", "break":
+			if line, ok := c.LineArg(args); ok {
+				if !d.StatementLines()[line] {
+					fmt.Fprintf(c.Out, "No statement at line %d\n", line)
+					continue
+				}
+				d.SetBreakpoint(line)
+				fmt.Fprintf(c.Out, "Breakpoint set at %s:%d\n", c.Path, line)
+			}
+		case "d", "delete":
+			if line, ok := c.LineArg(args); ok {
+				d.ClearBreakpoint(line)
+			}
+		case "info":
+			for _, line := range d.BreakpointLines() {
+				fmt.Fprintf(c.Out, "Breakpoint at %s:%d\n", c.Path, line)
+			}
+		case "bt", "backtrace":
+			for i, frame := range d.StackTrace() {
+				marker := " "
+				if i == c.frame {
+					marker = "*"
+				}
+				fmt.Fprintf(c.Out, "%s#%d %s at %s:%d\n", marker, i, frame.Name, c.Path, frame.Line)
+			}
+		case "frame":
+			if n, ok := c.LineArg(args); ok {
+				if n < 0 || n >= len(d.StackTrace()) {
+					fmt.Fprintf(c.Out, "No frame %d\n", n)
+					continue
+				}
+				c.frame = n
+				frame := d.StackTrace()[n]
+				fmt.Fprintf(c.Out, "#%d %s\n", n, frame.Name)
+				c.PrintLine(frame.Line)
+			}
+		case "l", "locals":
+			frame := d.StackTrace()[c.frame]
+			for _, scope := range d.Scopes(frame) {
+				if scope.Global {
+					break
+				}
+				for _, name := range SortedNames(scope.Env) {
+					fmt.Fprintf(c.Out, "%s = %s\n", name, visitor.Inspect(scope.Env.Values[name]))
+				}
+			}
+		case "p", "print":
+			if len(args) == 0 {
+				fmt.Fprintln(c.Out, "Usage: print EXPR")
+				continue
+			}
+			frame := d.StackTrace()[c.frame]
+			value, err := d.Evaluate(strings.Join(args, " "), frame)
+			if err != nil {
+				// Parse errors are already reported by the parser
+				if runtimeError, ok := err.(*glox_error.RuntimeError); ok {
+					fmt.Fprintln(c.Out, runtimeError.Message())
+				}
+				continue
+			}
+			fmt.Fprintln(c.Out, visitor.Inspect(value))
+		case "list":
+			frame := d.StackTrace()[c.frame]
+			for i := frame.Line - 3; i <= frame.Line+3; i++ {
+				if i >= 1 && i <= len(c.Source) {
+					marker := " "
+					if i == frame.Line {
+						marker = ">"
+					}
+					fmt.Fprintf(c.Out, "%s%4d  %s\n", marker, i, c.Source[i-1])
+				}
+			}
+		case "q", "quit":
+			panic(quit{})
+		case "h", "help":
+			fmt.Fprint(c.Out, cliHelp)
+		default:
+			fmt.Fprintf(c.Out, "Unknown command %s, try help\n", command)
+		}
+	}
+}
+
+func (c *CLI) LineArg(args []string) (int, bool) {
+	if len(args) != 1 {
+		fmt.Fprintln(c.Out, "Expect a number")
+		return 0, false
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Fprintln(c.Out, "Expect a number")
+		return 0, false
+	}
+	return n, true
+}
+
+func (c *CLI) PrintLine(line int) {
+	if line >= 1 && line <= len(c.Source) {
+		fmt.Fprintf(c.Out, "%s:%d  %s\n", c.Path, line, strings.TrimSpace(c.Source[line-1]))
+	}
+}
@@ -0,0 +1,258 @@
+package debugger
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/environment"
+	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/visitor"
+)
+
+const (
+	ModeContinue = iota
+	ModeStep
+	ModeNext
+	ModeFinish
+	ModePause
+)
+
+const (
+	ReasonStep       = "step"
+	ReasonBreakpoint = "breakpoint"
+	ReasonPause      = "pause"
+)
+
+// Frontend presents a stopped program to the user. Stopped is called on the
+// interpreter goroutine and the program resumes when it returns, after one
+// of Continue, Step, Next or Finish has been called.
+type Frontend interface {
+	Stopped(d *Debugger, reason string, line int)
+}
+
+// Frame is an entry of the Lox call stack, the innermost frame comes first.
+type Frame struct {
+	Name string
+	Line int
+	Env  *environment.Env
+}
+
+// Scope is one environment of a frame.
+type Scope struct {
+	Name   string
+	Env    *environment.Env
+	Global bool
+}
+
+// Debugger is a StatementHook that stops the interpreter on breakpoints and
+// after stepping commands. Breakpoints and Pause may be used from another
+// goroutine while the program runs.
+type Debugger struct {
+	Interpreter *visitor.AstInterpreter
+	Lines       map[ast.Node]int
+	Breakpoints map[int]bool
+	Frontend    Frontend
+
+	mu    sync.Mutex
+	mode  int
+	depth int
+
+	line     int
+	env      *environment.Env
+	lastLine int
+	lastNode ast.Node
+}
+
+func NewDebugger(interpreter *visitor.AstInterpreter, lines map[ast.Node]int, frontend Frontend) *Debugger {
+	d := &Debugger{
+		Interpreter: interpreter,
+		Lines:       lines,
+		Breakpoints: make(map[int]bool),
+		Frontend:    frontend,
+		mode:        ModeContinue,
+	}
+	interpreter.AddHook(d)
+	return d
+}
+
+func (d *Debugger) BeforeStatement(node ast.Node, env *environment.Env) {
+	// Blocks only group statements, stopping on them would stop twice on
+	// the same code.
+	if _, ok := node.(*ast.BlockStatement); ok {
+		return
+	}
+	line, ok := d.Lines[node]
+	if !ok {
+		return
+	}
+	enterLine := line != d.lastLine || node == d.lastNode
+	d.lastLine, d.lastNode = line, node
+	d.line, d.env = line, env
+
+	d.mu.Lock()
+	depth := d.Depth()
+	reason := ""
+	switch d.mode {
+	case ModeStep:
+		reason = ReasonStep
+	case ModeNext:
+		if depth <= d.depth {
+			reason = ReasonStep
+		}
+	case ModeFinish:
+		if depth < d.depth {
+			reason = ReasonStep
+		}
+	case ModePause:
+		reason = ReasonPause
+	}
+	if reason == "" && enterLine && d.Breakpoints[line] {
+		reason = ReasonBreakpoint
+	}
+	if reason != "" {
+		d.mode = ModeContinue
+	}
+	d.mu.Unlock()
+
+	if reason != "" {
+		d.Frontend.Stopped(d, reason, line)
+	}
+}
+
+// Depth is the number of active Lox function calls.
+func (d *Debugger) Depth() int {
+	return len(d.Interpreter.Frames)
+}
+
+func (d *Debugger) setMode(mode int) {
+	d.mu.Lock()
+	d.mode = mode
+	d.depth = d.Depth()
+	d.mu.Unlock()
+}
+
+func (d *Debugger) Continue() {
+	d.setMode(ModeContinue)
+}
+
+// Step stops at the next statement, entering calls.
+func (d *Debugger) Step() {
+	d.setMode(ModeStep)
+}
+
+// Next stops at the next statement of the current function.
+func (d *Debugger) Next() {
+	d.setMode(ModeNext)
+}
+
+// Finish stops once the current function returned.
+func (d *Debugger) Finish() {
+	d.setMode(ModeFinish)
+}
+
+// Pause stops a running program at its next statement.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	d.mode = ModePause
+	d.mu.Unlock()
+}
+
+func (d *Debugger) SetBreakpoint(line int) {
+	d.mu.Lock()
+	d.Breakpoints[line] = true
+	d.mu.Unlock()
+}
+
+func (d *Debugger) ClearBreakpoint(line int) {
+	d.mu.Lock()
+	delete(d.Breakpoints, line)
+	d.mu.Unlock()
+}
+
+func (d *Debugger) ClearBreakpoints() {
+	d.mu.Lock()
+	d.Breakpoints = make(map[int]bool)
+	d.mu.Unlock()
+}
+
+// BreakpointLines returns the sorted lines that have a breakpoint.
+func (d *Debugger) BreakpointLines() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var lines []int
+	for line := range d.Breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// StatementLines returns whether a statement starts on each line, a
+// breakpoint on any other line would never be hit.
+func (d *Debugger) StatementLines() map[int]bool {
+	lines := make(map[int]bool)
+	for node, line := range d.Lines {
+		if _, ok := node.(*ast.BlockStatement); !ok {
+			lines[line] = true
+		}
+	}
+	return lines
+}
+
+func (d *Debugger) StackTrace() []Frame {
+	frames := d.Interpreter.Frames
+	name := "<script>"
+	if len(frames) > 0 {
+		name = frames[len(frames)-1].Name()
+	}
+	stack := []Frame{{Name: name, Line: d.line, Env: d.env}}
+	for i := len(frames) - 1; i >= 0; i-- {
+		name := "<script>"
+		if i > 0 {
+			name = frames[i-1].Name()
+		}
+		stack = append(stack, Frame{
+			Name: name,
+			Line: frames[i].CallSite.Line,
+			Env:  frames[i].CallerEnv,
+		})
+	}
+	return stack
+}
+
+// Scopes walks the environment of a frame from the innermost scope up to
+// the globals.
+func (d *Debugger) Scopes(frame Frame) []Scope {
+	var scopes []Scope
+	for env := frame.Env; env != nil; env = env.Parent {
+		if env.Parent == nil {
+			scopes = append(scopes, Scope{Name: "Globals", Env: env, Global: true})
+		} else {
+			scopes = append(scopes, Scope{Name: "Locals", Env: env})
+		}
+	}
+	return scopes
+}
+
+// Evaluate parses and evaluates an expression in the given frame.
+func (d *Debugger) Evaluate(expression string, frame Frame) (interface{}, error) {
+	lex := lexer.NewLexer(expression)
+	lex.Lex()
+	parser := ast.NewParser(lex.Tokens)
+	node, err := parser.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return d.Interpreter.Evaluate(node, frame.Env)
+}
+
+// SortedNames returns the variable names of env in a stable order.
+func SortedNames(env *environment.Env) []string {
+	var names []string
+	for name := range env.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,141 @@
+package debugger_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/debugger"
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/utils"
+	"github.com/jameslahm/glox/visitor"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+const program = `class Point {
+  init(x) {
+    this.x = x;
+  }
+}
+
+fun scale(p, by) {
+  var result = p.x * by;
+  return result;
+}
+
+var p = Point(2);
+print scale(p, 3);
+print "done";
+`
+
+// run debugs source, answering the CLI prompts with commands, and returns
+// everything the CLI and the program printed along with the result of Run.
+func run(t *testing.T, source string, commands string) (string, error) {
+	g := &glox.Glox{}
+	script, err := g.Compile(source)
+	assert.Equal(t, err, nil)
+
+	var out bytes.Buffer
+	interpreter := g.NewInterpreter(script)
+	interpreter.Stdout = &out
+	cli := debugger.NewCLI("main.lox", script.Source, strings.NewReader(commands), &out)
+	err = cli.Run(interpreter, script.Node, script.Lines)
+	return out.String(), err
+}
+
+func TestBreakpoint(t *testing.T) {
+	out, err := run(t, program, `break 8
+break 6
+continue
+backtrace
+locals
+print p.x * by + 1
+frame 1
+print p
+next
+print result
+continue
+`)
+	assert.Equal(t, out, `main.lox:1  class Point {
+(glox) Breakpoint set at main.lox:8
+(glox) No statement at line 6
+(glox) Breakpoint at main.lox:8
+main.lox:8  var result = p.x * by;
+(glox) *#0 scale at main.lox:8
+ #1 <script> at main.lox:13
+(glox) by = 3
+p = Point instance
+(glox) 7
+(glox) #1 <script>
+main.lox:13  print scale(p, 3);
+(glox) Point instance
+(glox) main.lox:9  return result;
+(glox) 6
+(glox) 6
+done
+Program finished
+`)
+	assert.Equal(t, err, nil)
+}
+
+func TestStep(t *testing.T) {
+	out, err := run(t, program, `next
+next
+step
+finish
+step
+next
+print nope
+continue
+`)
+	assert.Equal(t, out, `main.lox:1  class Point {
+(glox) main.lox:7  fun scale(p, by) {
+(glox) main.lox:12  var p = Point(2);
+(glox) main.lox:3  this.x = x;
+(glox) main.lox:13  print scale(p, 3);
+(glox) main.lox:8  var result = p.x * by;
+(glox) main.lox:9  return result;
+(glox) Undefined variable nope
+(glox) 6
+done
+Program finished
+`)
+	assert.Equal(t, err, nil)
+}
+
+func TestExit(t *testing.T) {
+	out, err := run(t, "print 1;\nexit(3);\nprint 2;\n", "continue\n")
+	assert.Equal(t, out, `main.lox:1  print 1;
+(glox) 1
+Program exited with status 3
+`)
+	exit, ok := err.(*visitor.Exit)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, exit.Code, 3)
+}
+
+func TestRuntimeError(t *testing.T) {
+	output := utils.Output
+	defer func() { utils.Output = output }()
+	var stderr bytes.Buffer
+	utils.Output = &stderr
+
+	out, err := run(t, "print 1;\nprint 1 + nil;\n", "continue\n")
+	assert.Equal(t, out, `main.lox:1  print 1;
+(glox) 1
+`)
+	_, ok := err.(*glox_error.RuntimeError)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, strings.Contains(stderr.String(), "[line 2]"), true)
+}
+
+func TestQuit(t *testing.T) {
+	out, err := run(t, program, "break 9\ncontinue\nquit\n")
+	assert.Equal(t, out, `main.lox:1  class Point {
+(glox) Breakpoint set at main.lox:9
+(glox) Breakpoint at main.lox:9
+main.lox:9  return result;
+(glox) `)
+	assert.Equal(t, err, debugger.ErrQuit)
+}
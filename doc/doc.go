@@ -0,0 +1,209 @@
+package doc
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/jameslahm/glox/ast"
+)
+
+const (
+	KindFunction = "function"
+	KindClass    = "class"
+	KindTrait    = "trait"
+	KindMethod   = "method"
+)
+
+// Entry is a top level function, class or trait, or a method of a class or
+// a trait, with the text of its /// doc comment.
+type Entry struct {
+	Kind      string
+	Name      string
+	Signature string
+	Doc       string
+	Line      int
+	Methods   []*Entry
+}
+
+// File holds the entries of one Lox file in source order.
+type File struct {
+	Path    string
+	Entries []*Entry
+}
+
+// Extract collects the top level functions, classes and traits of program.
+func Extract(path string, program ast.Node) *File {
+	file := &File{Path: path}
+	node, ok := program.(*ast.Program)
+	if !ok {
+		return file
+	}
+	for _, statement := range node.Statements {
+		switch declaration := statement.(type) {
+		case *ast.FuncDeclaration:
+			file.Entries = append(file.Entries, function(KindFunction, declaration))
+		case *ast.ClassDeclaration:
+			file.Entries = append(file.Entries, class(declaration))
+		case *ast.TraitDeclaration:
+			file.Entries = append(file.Entries, trait(declaration))
+		}
+	}
+	return file
+}
+
+func function(kind string, node *ast.FuncDeclaration) *Entry {
+	var params []string
+	for i, param := range node.Params {
+		if node.ParamTypes[i] != nil {
+			params = append(params, param.Lexeme+": "+node.ParamTypes[i].Name.Lexeme)
+		} else {
+			params = append(params, param.Lexeme)
+		}
+	}
+	signature := fmt.Sprintf("%s(%s)", node.Name.Lexeme, strings.Join(params, ", "))
+	if node.Kind == ast.GetterKind {
+		signature = node.Name.Lexeme
+	}
+	if node.ReturnType != nil {
+		signature += ": " + node.ReturnType.Name.Lexeme
+	}
+	switch {
+	case kind == KindFunction:
+		signature = "fun " + signature
+	case node.Kind == ast.ClassMethodKind:
+		signature = "class " + signature
+	case node.Kind == ast.SetterKind:
+		signature = "set " + signature
+	}
+	return &Entry{
+		Kind:      kind,
+		Name:      node.Name.Lexeme,
+		Signature: signature,
+		Doc:       node.Doc,
+		Line:      node.Name.Line,
+	}
+}
+
+func class(node *ast.ClassDeclaration) *Entry {
+	signature := "class " + node.Name.Lexeme
+	if node.SuperClass != nil {
+		signature += " < " + node.SuperClass.Name.Lexeme
+	}
+	if len(node.Traits) != 0 {
+		var traits []string
+		for _, trait := range node.Traits {
+			traits = append(traits, trait.Name.Lexeme)
+		}
+		signature += " with " + strings.Join(traits, ", ")
+	}
+	entry := &Entry{
+		Kind:      KindClass,
+		Name:      node.Name.Lexeme,
+		Signature: signature,
+		Doc:       node.Doc,
+		Line:      node.Name.Line,
+	}
+	for _, method := range node.Methods {
+		entry.Methods = append(entry.Methods, function(KindMethod, method))
+	}
+	return entry
+}
+
+func trait(node *ast.TraitDeclaration) *Entry {
+	entry := &Entry{
+		Kind:      KindTrait,
+		Name:      node.Name.Lexeme,
+		Signature: "trait " + node.Name.Lexeme,
+		Doc:       node.Doc,
+		Line:      node.Name.Line,
+	}
+	for _, method := range node.Methods {
+		entry.Methods = append(entry.Methods, function(KindMethod, method))
+	}
+	return entry
+}
+
+// WriteMarkdown writes the documentation of files as Markdown, doc comments
+// are copied as they are so they may use Markdown themselves.
+func WriteMarkdown(w io.Writer, files []*File) {
+	for i, file := range files {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "# %s\n", file.Path)
+		for _, entry := range file.Entries {
+			writeMarkdownEntry(w, "##", entry)
+			for _, method := range entry.Methods {
+				writeMarkdownEntry(w, "###", method)
+			}
+		}
+	}
+}
+
+func writeMarkdownEntry(w io.Writer, heading string, entry *Entry) {
+	fmt.Fprintf(w, "\n%s %s\n\n```lox\n%s\n```\n", heading, entry.Name, entry.Signature)
+	if entry.Doc != "" {
+		fmt.Fprintf(w, "\n%s\n", entry.Doc)
+	}
+}
+
+// paragraphs splits a doc comment at its blank lines.
+func paragraphs(doc string) []string {
+	var result []string
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			result = append(result, paragraph)
+		}
+	}
+	return result
+}
+
+var htmlTemplate = template.Must(template.New("doc").Funcs(template.FuncMap{
+	"paragraphs": paragraphs,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>glox doc</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: auto; }
+pre { background: #f4f4f4; padding: 0.5em; }
+.method { margin-left: 2em; }
+</style>
+</head>
+<body>
+{{- range .}}
+<h1>{{.Path}}</h1>
+<ul>
+{{- range .Entries}}
+<li><a href="#{{.Name}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+{{- range .Entries}}
+{{- $class := .Name}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+<pre>{{.Signature}}</pre>
+{{- range paragraphs .Doc}}
+<p>{{.}}</p>
+{{- end}}
+{{- range .Methods}}
+<div class="method">
+<h3 id="{{$class}}.{{.Name}}">{{.Name}}</h3>
+<pre>{{.Signature}}</pre>
+{{- range paragraphs .Doc}}
+<p>{{.}}</p>
+{{- end}}
+</div>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the documentation of files as a single page.
+func WriteHTML(w io.Writer, files []*File) error {
+	return htmlTemplate.Execute(w, files)
+}
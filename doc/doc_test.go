@@ -0,0 +1,71 @@
+package doc_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/doc"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+const source = `/* Shapes, /* nested */ comment */
+
+/// Adds two numbers.
+fun add(a: number, b) {
+  return a + b;
+}
+
+//// An ordinary comment.
+fun helper() {}
+
+/// A shape.
+/// With two lines.
+class Square < Shape with Named {
+  /// The area.
+  area(): number { return 1; }
+  /// A unit square.
+  class unit() { return Square(); }
+  side { return 1; }
+  set side(value) {}
+}
+class Shape {}
+
+/// Something with a name.
+trait Named {
+  /// The name.
+  name() { return "square"; }
+}
+`
+
+func TestExtract(t *testing.T) {
+	script, err := (&glox.Glox{}).Compile(source)
+	assert.Equal(t, err, nil)
+	file := doc.Extract("shapes.lox", script.Node)
+
+	assert.Equal(t, len(file.Entries), 5)
+	add, helper, square := file.Entries[0], file.Entries[1], file.Entries[2]
+	assert.Equal(t, add.Signature, "fun add(a: number, b)")
+	assert.Equal(t, add.Doc, "Adds two numbers.")
+	assert.Equal(t, add.Line, 4)
+	assert.Equal(t, helper.Doc, "")
+	assert.Equal(t, square.Kind, doc.KindClass)
+	assert.Equal(t, square.Signature, "class Square < Shape with Named")
+	assert.Equal(t, square.Doc, "A shape.\nWith two lines.")
+	assert.Equal(t, square.Methods[0].Signature, "area(): number")
+	assert.Equal(t, square.Methods[0].Doc, "The area.")
+	assert.Equal(t, square.Methods[1].Signature, "class unit()")
+	assert.Equal(t, square.Methods[1].Doc, "A unit square.")
+	assert.Equal(t, square.Methods[2].Signature, "side")
+	assert.Equal(t, square.Methods[3].Signature, "set side(value)")
+	named := file.Entries[4]
+	assert.Equal(t, named.Kind, doc.KindTrait)
+	assert.Equal(t, named.Signature, "trait Named")
+	assert.Equal(t, named.Doc, "Something with a name.")
+	assert.Equal(t, named.Methods[0].Signature, "name()")
+	assert.Equal(t, named.Methods[0].Doc, "The name.")
+
+	var buf bytes.Buffer
+	doc.WriteMarkdown(&buf, []*doc.File{file})
+	assert.Equal(t, bytes.Contains(buf.Bytes(), []byte("## add\n\n```lox\nfun add(a: number, b)\n```\n\nAdds two numbers.\n")), true)
+}
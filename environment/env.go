@@ -10,7 +10,9 @@ import (
 
 type Env struct {
 	Values map[string]interface{}
-	Parent *Env
+	// Constants are the names defined with const, which can't be assigned
+	Constants map[string]bool
+	Parent    *Env
 }
 
 func NewEnvironment(parent *Env) *Env {
@@ -22,6 +24,16 @@ func NewEnvironment(parent *Env) *Env {
 
 func (e *Env) Define(name string, value interface{}) {
 	e.Values[name] = value
+	delete(e.Constants, name)
+}
+
+// DefineConst defines a name that Assign refuses to change.
+func (e *Env) DefineConst(name string, value interface{}) {
+	e.Values[name] = value
+	if e.Constants == nil {
+		e.Constants = make(map[string]bool)
+	}
+	e.Constants[name] = true
 }
 
 func (e *Env) Assign(token lexer.Token, value interface{}, distance int) {
@@ -29,6 +41,9 @@ func (e *Env) Assign(token lexer.Token, value interface{}, distance int) {
 	for i := 0; i < distance; i++ {
 		env = env.Parent
 	}
+	if env.Constants[token.Lexeme] {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.ASSIGN_TO_CONSTANT, token.Lexeme), token))
+	}
 	if _, ok := env.Values[token.Lexeme]; ok {
 		env.Values[token.Lexeme] = value
 	} else {
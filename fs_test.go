@@ -0,0 +1,70 @@
+package glox_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/utils"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestFileSystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	g := &glox.Glox{Stdout: &stdout, FileSystem: true}
+	err = g.Run(`
+var dir = ` + strconv.Quote(dir) + `;
+var file = path.join(dir, "notes.txt");
+print fs.exists(file);
+fs.writeFile(file, "one\n");
+fs.appendFile(file, "two");
+print fs.readFile(file);
+fs.mkdir(path.join(dir, "sub/deeper"));
+print fs.listDir(dir);
+fs.remove(file);
+print fs.exists(file);
+`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stdout.String(), "false\none\ntwo\n[\"notes.txt\", \"sub\"]\nfalse\n")
+	_, err = os.Stat(filepath.Join(dir, "sub", "deeper"))
+	assert.Equal(t, err, nil)
+}
+
+func TestFileSystemError(t *testing.T) {
+	var diagnostics bytes.Buffer
+	output := utils.Output
+	utils.Output = &diagnostics
+	defer func() {
+		utils.Output = output
+	}()
+	g := &glox.Glox{Stdout: ioutil.Discard, FileSystem: true}
+	err := g.Run(`fs.readFile("testdata/missing.txt");`)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, diagnostics.String(), "fs.readFile: open testdata/missing.txt: no such file or directory\n[line 1]\n")
+}
+
+func TestFileSystemCatch(t *testing.T) {
+	var stdout bytes.Buffer
+	g := &glox.Glox{Stdout: &stdout, FileSystem: true}
+	err := g.Run(`
+var config = "defaults";
+try {
+  config = fs.readFile("testdata/missing.txt");
+} catch (error) {
+  print error;
+}
+print config;
+`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stdout.String(), "fs.readFile: open testdata/missing.txt: no such file or directory\ndefaults\n")
+}
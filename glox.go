@@ -2,57 +2,182 @@ package glox
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
 
 	"github.com/jameslahm/glox/ast"
 	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/utils"
 	"github.com/jameslahm/glox/visitor"
 )
 
 type Glox struct {
+	// Stdout receives the output of print statements, os.Stdout when nil
+	Stdout io.Writer
+	// Stdin is read by the input and readLine natives, os.Stdin when nil
+	Stdin io.Reader
+	// Args are the arguments of the script returned by args
+	Args []string
+	// Time is the source of the time natives, the system time when nil
+	Time visitor.TimeSource
+	// FileSystem lets scripts read and write files through the fs module,
+	// embeddings running untrusted scripts leave it off
+	FileSystem bool
+
+	stdin *bufio.Reader
 }
 
-func (g *Glox) RunFile(path string) {
+// ErrCompile is returned for scripts with syntax, resolution or type errors,
+// the errors themselves have already been reported.
+var ErrCompile = errors.New("compile failed")
+
+// Script is a parsed and resolved program ready to be interpreted.
+type Script struct {
+	Source    string
+	Node      ast.Node
+	Lines     map[ast.Node]int
+	Branches  map[ast.Node]int
+	Columns   map[ast.Node]int
+	Distances map[ast.Node]int
+}
+
+func (g *Glox) RunFile(path string) error {
 	buf, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatalf("ReadFile error: %v\n", err)
+		return err
+	}
+	return g.Run(string(buf))
+}
+
+// StdinReader returns the reader of Stdin shared by the REPL, the debugger
+// and every interpreter, so none of them loses input another one buffered.
+func (g *Glox) StdinReader() *bufio.Reader {
+	if g.stdin == nil {
+		var in io.Reader = os.Stdin
+		if g.Stdin != nil {
+			in = g.Stdin
+		}
+		g.stdin = bufio.NewReader(in)
 	}
-	g.Run(string(buf))
+	return g.stdin
 }
 
 func (g *Glox) RunPrompt() {
-	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Print(">> ")
-	for scanner.Scan() {
-		line := scanner.Text()
-		g.Run(line)
+	stdin := g.StdinReader()
+	for {
 		fmt.Print(">> ")
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			return
+		}
+		if exit, ok := g.Run(line).(*visitor.Exit); ok {
+			os.Exit(exit.Code)
+		}
+	}
+}
+
+// Run runs script and returns ErrCompile, the runtime error that stopped
+// it, after reporting it, or the *visitor.Exit of a call to exit.
+func (g *Glox) Run(script string) error {
+	s, err := g.Compile(script)
+	if err != nil {
+		return err
+	}
+
+	interpreter := g.NewInterpreter(s)
+
+	if err := interpreter.Interpret(s.Node); err != nil {
+		if _, ok := err.(*visitor.Exit); !ok {
+			fmt.Fprintln(utils.Output, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (g *Glox) CompileFile(path string) (*Script, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
+	return g.Compile(string(buf))
 }
 
-func (g *Glox) Run(script string) {
+// Compile lexes, parses and resolves script.
+func (g *Glox) Compile(script string) (*Script, error) {
 	lex := lexer.NewLexer(script)
 	lex.Lex()
 	parser := ast.NewParser(lex.Tokens)
 	node := parser.Parse()
 
-	if len(parser.Errors) != 0 {
-		fmt.Println("Error: parse failed")
-		return
+	if lex.HasError() || len(parser.Errors) != 0 {
+		return nil, ErrCompile
 	}
 
 	resolver := visitor.NewResolver()
 	node.Accept(resolver)
 
 	if len(resolver.Errors) != 0 {
-		fmt.Println("Error: resolve failed")
-		return
+		return nil, ErrCompile
 	}
 
-	interpreter := visitor.NewAstInterpreter(resolver.VariableBindingDistances)
+	return &Script{
+		Source:    script,
+		Node:      node,
+		Lines:     parser.Lines,
+		Branches:  parser.Branches,
+		Columns:   parser.Columns,
+		Distances: resolver.VariableBindingDistances,
+	}, nil
+}
 
-	node.Accept(interpreter)
+func (g *Glox) NewInterpreter(s *Script) *visitor.AstInterpreter {
+	interpreter := visitor.NewAstInterpreter(s.Distances)
+	if g.Stdout != nil {
+		interpreter.Stdout = g.Stdout
+	}
+	interpreter.Stdin = g.StdinReader()
+	if g.Time != nil {
+		interpreter.Time = g.Time
+	}
+	interpreter.Args = g.Args
+	interpreter.FileSystem = g.FileSystem
+	return interpreter
+}
+
+// CheckFile type checks the script at path, it returns ErrCompile when the
+// script has errors.
+func (g *Glox) CheckFile(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !g.Check(string(buf)) {
+		return ErrCompile
+	}
+	return nil
+}
+
+// Check runs the static type checker over script without executing it.
+func (g *Glox) Check(script string) bool {
+	lex := lexer.NewLexer(script)
+	lex.Lex()
+	parser := ast.NewParser(lex.Tokens)
+	node := parser.Parse()
+
+	if lex.HasError() || len(parser.Errors) != 0 {
+		return false
+	}
+
+	checker := visitor.NewTypeChecker()
+	node.Accept(checker)
+
+	if len(checker.Errors) != 0 {
+		fmt.Fprintln(utils.Output, "Error: type check failed")
+		return false
+	}
+	return true
 }
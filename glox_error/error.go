@@ -1,6 +1,9 @@
 package glox_error
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/jameslahm/glox/lexer"
 )
 
@@ -15,3 +18,15 @@ func NewRuntimeError(message string, token lexer.Token) *RuntimeError {
 		token:   token,
 	}
 }
+
+func (e *RuntimeError) Message() string {
+	return strings.TrimSpace(e.message)
+}
+
+func (e *RuntimeError) Token() lexer.Token {
+	return e.token
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s\n[line %d]", e.Message(), e.token.Line)
+}
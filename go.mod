@@ -2,7 +2,4 @@ module github.com/jameslahm/glox
 
 go 1.15
 
-require (
-	github.com/spf13/cast v1.3.1
-	gopkg.in/go-playground/assert.v1 v1.2.1
-)
+require gopkg.in/go-playground/assert.v1 v1.2.1
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/jameslahm/glox/utils"
 )
@@ -13,9 +15,18 @@ type Lexer struct {
 	Source string
 	Tokens []Token
 
-	start   int
-	current int
-	line    int
+	start     int
+	current   int
+	line      int
+	lineStart int
+	column    int
+
+	// interpolations holds the depth of braces inside each string
+	// interpolation being lexed
+	interpolations []int
+
+	// doc holds the lines of the /// comments not yet attached to a token
+	doc []string
 
 	hasError bool
 }
@@ -40,8 +51,13 @@ func (lexer *Lexer) ShowTokens() {
 func (lexer *Lexer) Lex() {
 	for !lexer.IsAtEnd() {
 		lexer.start = lexer.current
+		// Columns count characters, not bytes
+		lexer.column = utf8.RuneCountInString(lexer.Source[lexer.lineStart:lexer.current]) + 1
 		lexer.Scan()
 	}
+	if len(lexer.interpolations) != 0 {
+		lexer.Error(utils.UNTERMINATED_INTERPOLATION)
+	}
 }
 
 func (lexer *Lexer) Scan() {
@@ -52,26 +68,94 @@ func (lexer *Lexer) Scan() {
 	case ')':
 		lexer.AddToken(RIGHT_PAREN, nil)
 	case '{':
+		if depth := len(lexer.interpolations); depth != 0 {
+			lexer.interpolations[depth-1]++
+		}
 		lexer.AddToken(LEFT_BRACE, nil)
 	case '}':
+		if depth := len(lexer.interpolations); depth != 0 {
+			if lexer.interpolations[depth-1] == 0 {
+				// The brace ends the interpolation, the string goes on
+				lexer.interpolations = lexer.interpolations[:depth-1]
+				if lexer.Tokens[len(lexer.Tokens)-1].Type == INTERPOLATION {
+					lexer.Error(utils.EMPTY_INTERPOLATION)
+				}
+				lexer.start = lexer.current
+				lexer.AddStringToken()
+				break
+			}
+			lexer.interpolations[depth-1]--
+		}
 		lexer.AddToken(RIGHT_BRACE, nil)
+	case '[':
+		lexer.AddToken(LEFT_BRACKET, nil)
+	case ']':
+		lexer.AddToken(RIGHT_BRACKET, nil)
 	case ',':
 		lexer.AddToken(COMMA, nil)
 	case '.':
 		lexer.AddToken(DOT, nil)
 	case '-':
-		lexer.AddToken(MINUS, nil)
+		if lexer.Match('=') {
+			lexer.AddToken(MINUS_EQUAL, nil)
+		} else {
+			lexer.AddToken(MINUS, nil)
+		}
 	case '+':
-		lexer.AddToken(PLUS, nil)
+		if lexer.Match('=') {
+			lexer.AddToken(PLUS_EQUAL, nil)
+		} else {
+			lexer.AddToken(PLUS, nil)
+		}
 	case ';':
 		lexer.AddToken(SEMICOLON, nil)
+	case ':':
+		lexer.AddToken(COLON, nil)
 	case '*':
-		lexer.AddToken(STAR, nil)
+		if lexer.Match('*') {
+			lexer.AddToken(STAR_STAR, nil)
+		} else if lexer.Match('=') {
+			lexer.AddToken(STAR_EQUAL, nil)
+		} else {
+			lexer.AddToken(STAR, nil)
+		}
+	case '%':
+		lexer.AddToken(PERCENT, nil)
+	case '?':
+		if lexer.Match('?') {
+			lexer.AddToken(QUESTION_QUESTION, nil)
+		} else if lexer.Match('.') {
+			lexer.AddToken(QUESTION_DOT, nil)
+		} else {
+			lexer.AddToken(QUESTION, nil)
+		}
+	case '&':
+		lexer.AddToken(AMPERSAND, nil)
+	case '|':
+		lexer.AddToken(PIPE, nil)
+	case '^':
+		lexer.AddToken(CARET, nil)
+	case '~':
+		if lexer.Match('/') {
+			lexer.AddToken(TILDE_SLASH, nil)
+		} else {
+			lexer.AddToken(TILDE, nil)
+		}
 	case '/':
 		if lexer.Match('/') {
-			for !lexer.Match('\n') && !lexer.IsAtEnd() {
+			// /// starts a doc comment, //// is an ordinary comment
+			isDoc := lexer.Peek() == '/' && lexer.PeekNext() != '/'
+			for lexer.Peek() != '\n' && !lexer.IsAtEnd() {
 				lexer.Advance()
 			}
+			if isDoc {
+				text := lexer.Source[lexer.start+3 : lexer.current]
+				lexer.doc = append(lexer.doc, strings.TrimPrefix(strings.TrimRight(text, " \t\r"), " "))
+			}
+		} else if lexer.Match('*') {
+			lexer.BlockComment()
+		} else if lexer.Match('=') {
+			lexer.AddToken(SLASH_EQUAL, nil)
 		} else {
 			lexer.AddToken(SLASH, nil)
 		}
@@ -90,13 +174,16 @@ func (lexer *Lexer) Scan() {
 	case '<':
 		if lexer.Match('=') {
 			lexer.AddTokenWithLexeme(LESS_EQUAL, nil, "<=")
-			lexer.Advance()
+		} else if lexer.Match('<') {
+			lexer.AddToken(LESS_LESS, nil)
 		} else {
 			lexer.AddToken(LESS, nil)
 		}
 	case '>':
 		if lexer.Match('=') {
 			lexer.AddTokenWithLexeme(GREATER_EQUAL, nil, ">=")
+		} else if lexer.Match('>') {
+			lexer.AddToken(GREATER_GREATER, nil)
 		} else {
 			lexer.AddToken(GREATER, nil)
 		}
@@ -105,11 +192,17 @@ func (lexer *Lexer) Scan() {
 	case '\t':
 		break
 	case '\n':
-		lexer.line++
+		lexer.NewLine()
 		break
 	case '"':
 		lexer.start++
 		lexer.AddStringToken()
+	case '#':
+		if utils.IsAlpha(lexer.Peek()) {
+			lexer.AddPrivateIdentifierToken()
+			break
+		}
+		lexer.Error(fmt.Sprintf("%s %c", utils.UNEXPECTED_CHARACTER_MESSAGE, c))
 	default:
 		if utils.IsDigit(c) {
 			lexer.AddNumberToken()
@@ -117,95 +210,325 @@ func (lexer *Lexer) Scan() {
 		} else if utils.IsAlpha(c) {
 			lexer.AddIdentifierToken()
 			break
+		} else if c == utf8.RuneError && lexer.current-lexer.start == 1 {
+			// Advance reported the invalid encoding
+			break
 		}
-		lexer.hasError = true
-		utils.Error(lexer.line, fmt.Sprintf("%s %c", utils.UNEXPECTED_CHARACTER_MESSAGE, c))
+		lexer.Error(fmt.Sprintf("%s %c", utils.UNEXPECTED_CHARACTER_MESSAGE, c))
 	}
 }
 
-func (lexer *Lexer) Advance() byte {
-	lexer.current++
-	return lexer.Source[lexer.current-1]
+// Advance consumes the next character of the source, which must be valid
+// UTF-8.
+func (lexer *Lexer) Advance() rune {
+	r, size := utf8.DecodeRuneInString(lexer.Source[lexer.current:])
+	lexer.current += size
+	if r == utf8.RuneError && size == 1 {
+		lexer.Error(utils.INVALID_UTF8)
+	}
+	return r
 }
 
-func (lexer *Lexer) Peek() byte {
-	if lexer.current >= len(lexer.Source) {
+func (lexer *Lexer) Peek() rune {
+	if lexer.IsAtEnd() {
 		return 0
 	}
-	return lexer.Source[lexer.current]
+	r, _ := utf8.DecodeRuneInString(lexer.Source[lexer.current:])
+	return r
 }
 
-func (lexer *Lexer) PeekNext() byte {
-	return lexer.Source[lexer.current+1]
+func (lexer *Lexer) PeekNext() rune {
+	if lexer.IsAtEnd() {
+		return 0
+	}
+	_, size := utf8.DecodeRuneInString(lexer.Source[lexer.current:])
+	if lexer.current+size >= len(lexer.Source) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(lexer.Source[lexer.current+size:])
+	return r
+}
+
+func (lexer *Lexer) NewLine() {
+	lexer.line++
+	lexer.lineStart = lexer.current
 }
 
 func (lexer *Lexer) AddToken(tokenType int, value interface{}) {
 	lexeme := lexer.Source[lexer.start:lexer.current]
-	token := NewToken(tokenType, lexeme, value, lexer.line)
-	lexer.Tokens = append(lexer.Tokens, *token)
+	lexer.AddTokenWithLexeme(tokenType, value, lexeme)
 }
 
 func (lexer *Lexer) AddTokenWithLexeme(tokenType int, value interface{}, lexeme string) {
-	token := NewToken(tokenType, lexeme, value, lexer.line)
+	token := NewToken(tokenType, lexeme, value, lexer.line, lexer.column)
+	if lexer.doc != nil {
+		token.Doc = strings.Join(lexer.doc, "\n")
+		lexer.doc = nil
+	}
 	lexer.Tokens = append(lexer.Tokens, *token)
 }
 
-func (lexer *Lexer) Match(c byte) bool {
-	if lexer.IsAtEnd() {
+// BlockComment skips a /* */ comment, which may contain nested ones. An
+// unterminated comment is reported at the line it starts on.
+func (lexer *Lexer) BlockComment() {
+	line := lexer.line
+	depth := 1
+	for depth > 0 {
+		if lexer.IsAtEnd() {
+			lexer.ErrorAt(line, utils.UNTERMINATED_COMMENT)
+			return
+		}
+		switch c := lexer.Advance(); {
+		case c == '\n':
+			lexer.NewLine()
+		case c == '/' && lexer.Match('*'):
+			depth++
+		case c == '*' && lexer.Match('/'):
+			depth--
+		}
+	}
+}
+
+// Match consumes the next character if it is c.
+func (lexer *Lexer) Match(c rune) bool {
+	if lexer.IsAtEnd() || lexer.Peek() != c {
 		return false
 	}
-	currentByte := lexer.Source[lexer.current]
-	return currentByte == c
+	lexer.Advance()
+	return true
+}
+
+func (lexer *Lexer) Error(message string) {
+	lexer.ErrorAt(lexer.line, message)
+}
+
+func (lexer *Lexer) ErrorAt(line int, message string) {
+	lexer.hasError = true
+	utils.Error(line, message)
+}
+
+// HasError reports whether an error was found in the source.
+func (lexer *Lexer) HasError() bool {
+	return lexer.hasError
 }
 
 func (lexer *Lexer) IsAtEnd() bool {
 	return lexer.current >= len(lexer.Source)
 }
 
+// AddStringToken scans a string literal up to its closing quote, or up to
+// the next interpolation which is added as an INTERPOLATION token.
 func (lexer *Lexer) AddStringToken() {
-	for !lexer.IsAtEnd() && !lexer.Match('"') {
+	var value strings.Builder
+	for !lexer.IsAtEnd() && lexer.Peek() != '"' {
 		c := lexer.Advance()
-		if c == '\n' {
-			lexer.line++
+		switch {
+		case c == '\n':
+			lexer.NewLine()
+			value.WriteRune(c)
+		case c == '\\':
+			lexer.Escape(&value)
+		case c == '$' && lexer.Peek() == '{':
+			lexer.Advance()
+			lexeme := lexer.Source[lexer.start : lexer.current-2]
+			lexer.AddTokenWithLexeme(INTERPOLATION, value.String(), lexeme)
+			lexer.interpolations = append(lexer.interpolations, 0)
+			return
+		default:
+			value.WriteRune(c)
 		}
 	}
 
 	if lexer.IsAtEnd() {
-		utils.Error(lexer.line, utils.UNTERMINATED_STRING)
-	} else {
-		lexer.Advance()
+		lexer.Error(utils.UNTERMINATED_STRING)
+		return
 	}
+	lexer.Advance()
 
 	lexeme := lexer.Source[lexer.start : lexer.current-1]
-	value := lexeme
-	lexer.AddTokenWithLexeme(STRING, value, lexeme)
+	lexer.AddTokenWithLexeme(STRING, value.String(), lexeme)
+}
+
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'"':  '"',
+	'\\': '\\',
+	'$':  '$',
+}
+
+// Escape writes the character of the escape sequence after a backslash,
+// unicode escapes are written as \u{1F600} or \u00e9.
+func (lexer *Lexer) Escape(value *strings.Builder) {
+	if lexer.IsAtEnd() {
+		return
+	}
+	c := lexer.Advance()
+	if escaped, ok := escapes[c]; ok {
+		value.WriteRune(escaped)
+		return
+	}
+	if c != 'u' {
+		lexer.Error(fmt.Sprintf(utils.INVALID_ESCAPE, c))
+		return
+	}
+
+	var digits string
+	if lexer.Match('{') {
+		start := lexer.current
+		for utils.IsHexDigit(lexer.Peek()) {
+			lexer.Advance()
+		}
+		digits = lexer.Source[start:lexer.current]
+		if !lexer.Match('}') || len(digits) == 0 || len(digits) > 6 {
+			lexer.Error(utils.INVALID_UNICODE_ESCAPE)
+			return
+		}
+	} else {
+		start := lexer.current
+		for i := 0; i < 4 && utils.IsHexDigit(lexer.Peek()); i++ {
+			lexer.Advance()
+		}
+		digits = lexer.Source[start:lexer.current]
+		if len(digits) != 4 {
+			lexer.Error(utils.INVALID_UNICODE_ESCAPE)
+			return
+		}
+	}
+	code, _ := strconv.ParseUint(digits, 16, 32)
+	r := rune(code)
+	if !utf8.ValidRune(r) {
+		lexer.Error(utils.INVALID_UNICODE_ESCAPE)
+		return
+	}
+	value.WriteRune(r)
+}
+
+var bases = map[rune]int{
+	'x': 16,
+	'X': 16,
+	'b': 2,
+	'B': 2,
+	'o': 8,
+	'O': 8,
 }
 
+// AddNumberToken scans a decimal number like 1_000, 1.5 or 1e-9, or an
+// integer with a base prefix like 0xFF, 0b101 or 0o17. Integers are int64
+// values and everything else, as well as integers that don't fit, float64.
 func (lexer *Lexer) AddNumberToken() {
-	for utils.IsDigit(lexer.Peek()) {
+	if base, ok := bases[lexer.Peek()]; ok && lexer.Source[lexer.start] == '0' {
 		lexer.Advance()
+		lexer.AddIntegerToken(base)
+		return
 	}
 
+	lexer.Digits()
+	isFloat := false
 	if lexer.Peek() == '.' && utils.IsDigit(lexer.PeekNext()) {
 		lexer.Advance()
+		lexer.Digits()
+		isFloat = true
+	}
+	if lexer.Peek() == 'e' || lexer.Peek() == 'E' {
+		mark := lexer.current
 		lexer.Advance()
+		if !lexer.Match('+') {
+			lexer.Match('-')
+		}
+		if utils.IsDigit(lexer.Peek()) {
+			lexer.Digits()
+			isFloat = true
+		} else {
+			// Not an exponent, the e starts an identifier
+			lexer.current = mark
+		}
+	}
 
-		for utils.IsDigit(lexer.Peek()) {
-			lexer.Advance()
+	lexeme := lexer.Source[lexer.start:lexer.current]
+	for _, digits := range strings.FieldsFunc(lexeme, func(c rune) bool { return strings.ContainsRune(".eE+-", c) }) {
+		if !validDigits(digits, utils.IsDigit) {
+			lexer.Error(fmt.Sprintf("%s %s", utils.INVALID_NUMBER, lexeme))
+			lexer.AddToken(NUMBER, int64(0))
+			return
+		}
+	}
+	text := strings.Replace(lexeme, "_", "", -1)
+	if !isFloat {
+		if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+			lexer.AddToken(NUMBER, value)
+			return
 		}
 	}
+	// Floats out of range become infinity or zero
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil && err.(*strconv.NumError).Err != strconv.ErrRange {
+		lexer.Error(fmt.Sprintf("%s %s", utils.INVALID_NUMBER, lexeme))
+	}
+	lexer.AddToken(NUMBER, value)
+}
 
+// AddIntegerToken scans the digits of an integer after its base prefix.
+func (lexer *Lexer) AddIntegerToken(base int) {
+	for utils.IsAlphaDigit(lexer.Peek()) {
+		lexer.Advance()
+	}
 	lexeme := lexer.Source[lexer.start:lexer.current]
-	value, err := strconv.ParseFloat(lexeme, 64)
+	digits := lexeme[2:]
+	isDigit := func(c rune) bool {
+		value, err := strconv.ParseInt(string(c), base, 64)
+		return err == nil && value < int64(base)
+	}
+	if !validDigits(digits, isDigit) {
+		lexer.Error(fmt.Sprintf("%s %s", utils.INVALID_NUMBER, lexeme))
+		lexer.AddToken(NUMBER, int64(0))
+		return
+	}
+	text := strings.Replace(digits, "_", "", -1)
+	if value, err := strconv.ParseInt(text, base, 64); err == nil {
+		lexer.AddToken(NUMBER, value)
+		return
+	}
+	value, err := strconv.ParseUint(text, base, 64)
 	if err != nil {
-		utils.Error(lexer.line, fmt.Sprintf("%s %s", utils.INVALID_NUMBER, lexeme))
+		lexer.Error(fmt.Sprintf("%s %s", utils.INVALID_NUMBER, lexeme))
 	}
+	lexer.AddToken(NUMBER, float64(value))
+}
 
-	lexer.AddToken(NUMBER, value)
+// Digits scans decimal digits, which may be separated by underscores.
+func (lexer *Lexer) Digits() {
+	for utils.IsDigit(lexer.Peek()) || lexer.Peek() == '_' {
+		lexer.Advance()
+	}
+}
+
+// validDigits reports whether digits is made of digits separated by single
+// underscores.
+func validDigits(digits string, isDigit func(c rune) bool) bool {
+	if digits == "" || strings.HasPrefix(digits, "_") || strings.HasSuffix(digits, "_") || strings.Contains(digits, "__") {
+		return false
+	}
+	for _, c := range digits {
+		if c != '_' && !isDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddPrivateIdentifierToken scans the name after a #.
+func (lexer *Lexer) AddPrivateIdentifierToken() {
+	for utils.IsAlphaDigit(lexer.Peek()) {
+		lexer.Advance()
+	}
+	lexer.AddToken(PRIVATE_IDENTIFIER, nil)
 }
 
 func (lexer *Lexer) AddIdentifierToken() {
-	for utils.IsAlpha(lexer.Peek()) || utils.IsDigit(lexer.Peek()) {
+	for utils.IsAlphaDigit(lexer.Peek()) {
 		lexer.Advance()
 	}
 
@@ -214,8 +537,12 @@ func (lexer *Lexer) AddIdentifierToken() {
 	switch lexeme {
 	case "and":
 		lexer.AddToken(AND, nil)
+	case "catch":
+		lexer.AddToken(CATCH, nil)
 	case "class":
 		lexer.AddToken(CLASS, nil)
+	case "const":
+		lexer.AddToken(CONST, nil)
 	case "else":
 		lexer.AddToken(ELSE, nil)
 	case "false":
@@ -226,6 +553,8 @@ func (lexer *Lexer) AddIdentifierToken() {
 		lexer.AddToken(FUN, nil)
 	case "if":
 		lexer.AddToken(IF, nil)
+	case "let":
+		lexer.AddToken(LET, nil)
 	case "nil":
 		lexer.AddToken(NIL, nil)
 	case "or":
@@ -238,12 +567,18 @@ func (lexer *Lexer) AddIdentifierToken() {
 		lexer.AddToken(SUPER, nil)
 	case "this":
 		lexer.AddToken(THIS, nil)
+	case "trait":
+		lexer.AddToken(TRAIT, nil)
 	case "true":
 		lexer.AddToken(TRUE, nil)
+	case "try":
+		lexer.AddToken(TRY, nil)
 	case "var":
 		lexer.AddToken(VAR, nil)
 	case "while":
 		lexer.AddToken(WHILE, nil)
+	case "with":
+		lexer.AddToken(WITH, nil)
 	default:
 		lexer.AddToken(IDENTIFIER, nil)
 	}
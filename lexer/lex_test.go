@@ -0,0 +1,25 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/jameslahm/glox/lexer"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestLexUnicode(t *testing.T) {
+	l := lexer.NewLexer("var café = \"日本\"; _x2")
+	l.Lex()
+	assert.Equal(t, l.HasError(), false)
+
+	var columns []int
+	var lexemes []string
+	for _, token := range l.Tokens {
+		columns = append(columns, token.Column)
+		lexemes = append(lexemes, token.Lexeme)
+	}
+	// Columns count characters, so multibyte ones don't shift later tokens
+	assert.Equal(t, lexemes, []string{"var", "café", "=", "日本", ";", "_x2"})
+	assert.Equal(t, columns, []int{1, 5, 10, 12, 16, 18})
+	assert.Equal(t, l.Tokens[3].Value, "日本")
+}
@@ -10,13 +10,34 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
 	DOT
 	MINUS
 	PLUS
 	SEMICOLON
+	COLON
 	SLASH
 	STAR
+	PERCENT
+	QUESTION
+	AMPERSAND
+	PIPE
+	CARET
+	TILDE
+
+	// Multi Character operator tokens
+	STAR_STAR
+	TILDE_SLASH
+	LESS_LESS
+	GREATER_GREATER
+	PLUS_EQUAL
+	MINUS_EQUAL
+	STAR_EQUAL
+	SLASH_EQUAL
+	QUESTION_QUESTION
+	QUESTION_DOT
 
 	// Relation And Logic tokens
 	AND
@@ -33,7 +54,11 @@ const (
 
 	// Literals tokens
 	IDENTIFIER
+	// PRIVATE_IDENTIFIER is a #name, which can only name class members
+	PRIVATE_IDENTIFIER
 	STRING
+	// INTERPOLATION is the part of a string literal before a ${ expression }
+	INTERPOLATION
 	NUMBER
 	NIL
 	FALSE
@@ -41,6 +66,8 @@ const (
 
 	// Keywords tokens
 	CLASS
+	TRAIT
+	WITH
 
 	// Control Flow tokens
 	IF
@@ -48,6 +75,8 @@ const (
 	FOR
 	WHILE
 	RETURN
+	TRY
+	CATCH
 
 	// Internal support tokens
 	PRINT
@@ -57,6 +86,8 @@ const (
 	// Declaration tokens
 	FUN
 	VAR
+	LET
+	CONST
 
 	EOF
 )
@@ -66,17 +97,21 @@ type Token struct {
 	Lexeme string
 	Value  interface{}
 	Line   int
+	Column int
+	// Doc is the text of the /// comments right before the token
+	Doc string
 }
 
 func (token *Token) String() string {
 	return fmt.Sprintf("%d %s %d", token.Type, token.Lexeme, token.Line)
 }
 
-func NewToken(tokenType int, lexeme string, value interface{}, line int) *Token {
+func NewToken(tokenType int, lexeme string, value interface{}, line int, column int) *Token {
 	return &Token{
 		Type:   tokenType,
 		Lexeme: lexeme,
 		Line:   line,
+		Column: column,
 		Value:  value,
 	}
 }
@@ -0,0 +1,85 @@
+package glox_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/visitor"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestInput(t *testing.T) {
+	var stdout bytes.Buffer
+	g := &glox.Glox{Stdout: &stdout, Stdin: strings.NewReader("Ada\r\nfirst\nlast")}
+	err := g.Run(`
+var name = input("Name? ");
+print "Hello " + name;
+print readLine();
+print readLine();
+print readLine();
+`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stdout.String(), "Name? Hello Ada\nfirst\nlast\nnil\n")
+}
+
+func TestInputSharedAcrossRuns(t *testing.T) {
+	var stdout bytes.Buffer
+	g := &glox.Glox{Stdout: &stdout, Stdin: strings.NewReader("first\nsecond\n")}
+	// Every run gets a new interpreter, the input one buffered must not be
+	// lost to the next
+	assert.Equal(t, g.Run(`print readLine();`), nil)
+	assert.Equal(t, g.Run(`print readLine();`), nil)
+	assert.Equal(t, stdout.String(), "first\nsecond\n")
+}
+
+func TestArgsAndEnv(t *testing.T) {
+	os.Setenv("GLOX_TEST_VALUE", "42")
+	defer os.Unsetenv("GLOX_TEST_VALUE")
+
+	var stdout bytes.Buffer
+	g := &glox.Glox{Stdout: &stdout, Args: []string{"-n", "file.txt"}}
+	err := g.Run(`
+print args();
+print env("GLOX_TEST_VALUE");
+print env("GLOX_TEST_UNSET");
+`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stdout.String(), "[\"-n\", \"file.txt\"]\n42\nnil\n")
+}
+
+func TestExit(t *testing.T) {
+	var stdout bytes.Buffer
+	g := &glox.Glox{Stdout: &stdout}
+	err := g.Run(`
+fun check(n) {
+  if (n > 1) exit(3);
+  print n;
+}
+for (var i = 0; i < 5; i = i + 1) check(i);
+print "unreachable";
+`)
+	exit, ok := err.(*visitor.Exit)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, exit.Code, 3)
+	assert.Equal(t, exit.Token.Line, 3)
+	assert.Equal(t, stdout.String(), "0\n1\n")
+}
+
+func TestExitIsNotCaught(t *testing.T) {
+	var stdout bytes.Buffer
+	g := &glox.Glox{Stdout: &stdout}
+	err := g.Run(`
+try {
+  exit(4);
+} catch (e) {
+  print "caught";
+}
+`)
+	exit, ok := err.(*visitor.Exit)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, exit.Code, 4)
+	assert.Equal(t, stdout.String(), "")
+}
@@ -0,0 +1,198 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// protobuf is a minimal encoder for the messages of pprof's profile.proto,
+// see https://github.com/google/pprof/blob/master/proto/profile.proto
+type protobuf struct {
+	data []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protobuf) uint64(field int, x uint64) {
+	b.varint(uint64(field)<<3 | 0)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *protobuf) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *protobuf) message(field int, encode func(m *protobuf)) {
+	m := &protobuf{}
+	encode(m)
+	b.bytes(field, m.data)
+}
+
+func (b *protobuf) packed(field int, values []uint64) {
+	m := &protobuf{}
+	for _, x := range values {
+		m.varint(x)
+	}
+	b.bytes(field, m.data)
+}
+
+// Field numbers of profile.proto
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileTimeNanos         = 9
+	profileDurationNanos     = 10
+	profilePeriodType        = 11
+	profilePeriod            = 12
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// pprofScriptName names the top level in profiles, pprof demangles names
+// like <script> to an empty string and shows them as <unknown>.
+const pprofScriptName = "(script)"
+
+type location struct {
+	function *FunctionStats
+	line     int
+}
+
+// WritePprof writes the recorded stacks as a gzipped pprof profile with a
+// call count and a time value per sample, so `go tool pprof` can show the
+// Lox call graph and flame graphs.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	table := []string{""}
+	stringIndex := map[string]int64{"": 0}
+	str := func(s string) int64 {
+		if i, ok := stringIndex[s]; ok {
+			return i
+		}
+		stringIndex[s] = int64(len(table))
+		table = append(table, s)
+		return stringIndex[s]
+	}
+
+	functionIDs := make(map[*FunctionStats]uint64)
+	var functions []*FunctionStats
+	locationIDs := make(map[location]uint64)
+	var locations []location
+
+	// Sort samples so the output doesn't depend on map iteration order
+	var keys []string
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	b := &protobuf{}
+	valueType := func(field int, typ string, unit string) {
+		b.message(field, func(m *protobuf) {
+			m.int64(valueTypeType, str(typ))
+			m.int64(valueTypeUnit, str(unit))
+		})
+	}
+	valueType(profileSampleType, "calls", "count")
+	valueType(profileSampleType, "time", "nanoseconds")
+
+	for _, key := range keys {
+		s := p.samples[key]
+		var ids []uint64
+		// pprof lists the leaf location first
+		for i := len(s.stack) - 1; i >= 0; i-- {
+			loc := location{function: s.stack[i].function, line: s.stack[i].line}
+			if _, ok := functionIDs[loc.function]; !ok {
+				functionIDs[loc.function] = uint64(len(functions) + 1)
+				functions = append(functions, loc.function)
+			}
+			id, ok := locationIDs[loc]
+			if !ok {
+				id = uint64(len(locations) + 1)
+				locationIDs[loc] = id
+				locations = append(locations, loc)
+			}
+			ids = append(ids, id)
+		}
+		b.message(profileSample, func(m *protobuf) {
+			m.packed(sampleLocationID, ids)
+			m.packed(sampleValue, []uint64{uint64(s.calls), uint64(s.time.Nanoseconds())})
+		})
+	}
+
+	for i, loc := range locations {
+		b.message(profileLocation, func(m *protobuf) {
+			m.uint64(locationID, uint64(i+1))
+			m.message(locationLine, func(line *protobuf) {
+				line.uint64(lineFunctionID, functionIDs[loc.function])
+				line.int64(lineLine, int64(loc.line))
+			})
+		})
+	}
+
+	for i, function := range functions {
+		name := function.Name
+		if function == p.script {
+			name = pprofScriptName
+		}
+		b.message(profileFunction, func(m *protobuf) {
+			m.uint64(functionID, uint64(i+1))
+			m.int64(functionName, str(name))
+			m.int64(functionSystemName, str(name))
+			m.int64(functionFilename, str(p.Path))
+			m.int64(functionStartLine, int64(function.Line))
+		})
+	}
+
+	b.int64(profileTimeNanos, p.start.UnixNano())
+	b.int64(profileDurationNanos, p.elapsed.Nanoseconds())
+	valueType(profilePeriodType, "time", "nanoseconds")
+	b.int64(profilePeriod, 1)
+	b.int64(profileDefaultSampleType, str("time"))
+
+	// The string table is written last, every string is known by now
+	for _, s := range table {
+		b.string(profileStringTable, s)
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(b.data); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,224 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/environment"
+	"github.com/jameslahm/glox/visitor"
+)
+
+const scriptName = "<script>"
+
+// FunctionStats are the totals of one Lox function. Inclusive time counts
+// the time spent in callees, Exclusive time only the function's own
+// statements.
+type FunctionStats struct {
+	Name      string
+	Line      int
+	Calls     int
+	Inclusive time.Duration
+	Exclusive time.Duration
+
+	active int
+}
+
+// LineStats are the totals of the statements starting on one line.
+type LineStats struct {
+	Hits int
+	Time time.Duration
+}
+
+type activation struct {
+	function *FunctionStats
+	line     int
+	start    time.Time
+}
+
+type sample struct {
+	stack []activation
+	calls int64
+	time  time.Duration
+}
+
+// Profiler is an interpreter hook that instruments every statement and every
+// Lox function call. Time between two events is charged to the line being
+// executed by the innermost function, so the recorded stacks add up to the
+// run time of the program.
+type Profiler struct {
+	Path      string
+	Lines     map[ast.Node]int
+	Functions map[*ast.FuncDeclaration]*FunctionStats
+	LineStats map[int]*LineStats
+
+	// Now returns the current time, tests replace it with a fake clock
+	Now func() time.Time
+
+	script  *FunctionStats
+	stack   []activation
+	samples map[string]*sample
+	last    time.Time
+	start   time.Time
+	elapsed time.Duration
+}
+
+func NewProfiler(path string, lines map[ast.Node]int) *Profiler {
+	return &Profiler{
+		Path:      path,
+		Lines:     lines,
+		Functions: make(map[*ast.FuncDeclaration]*FunctionStats),
+		LineStats: make(map[int]*LineStats),
+		Now:       time.Now,
+		script:    &FunctionStats{Name: scriptName, Line: 1, Calls: 1},
+		samples:   make(map[string]*sample),
+	}
+}
+
+// Start begins timing the program, it must be called right before the
+// program is interpreted.
+func (p *Profiler) Start() {
+	p.start = p.Now()
+	p.last = p.start
+	p.stack = []activation{{function: p.script, line: 1, start: p.start}}
+}
+
+// Stop ends timing the program.
+func (p *Profiler) Stop() {
+	now := p.Now()
+	p.flush(now)
+	p.elapsed = now.Sub(p.start)
+	p.script.Inclusive = p.elapsed
+}
+
+func (p *Profiler) BeforeStatement(node ast.Node, env *environment.Env) {
+	if _, ok := node.(*ast.BlockStatement); ok {
+		return
+	}
+	line, ok := p.Lines[node]
+	if !ok {
+		return
+	}
+	p.flush(p.Now())
+	p.stack[len(p.stack)-1].line = line
+	p.lineStats(line).Hits++
+}
+
+func (p *Profiler) EnterFunction(frame *visitor.CallFrame) {
+	now := p.Now()
+	p.flush(now)
+	function := p.function(frame.Function.Node)
+	function.Calls++
+	function.active++
+	p.stack = append(p.stack, activation{
+		function: function,
+		line:     function.Line,
+		start:    now,
+	})
+	p.sample().calls++
+}
+
+func (p *Profiler) ExitFunction(frame *visitor.CallFrame) {
+	now := p.Now()
+	p.flush(now)
+	top := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	top.function.active--
+	// Only the outermost activation of a recursive function counts,
+	// otherwise nested calls would be counted more than once.
+	if top.function.active == 0 {
+		top.function.Inclusive += now.Sub(top.start)
+	}
+}
+
+// flush charges the time since the last event to the current stack.
+func (p *Profiler) flush(now time.Time) {
+	elapsed := now.Sub(p.last)
+	p.last = now
+	if len(p.stack) == 0 {
+		return
+	}
+	top := p.stack[len(p.stack)-1]
+	top.function.Exclusive += elapsed
+	p.lineStats(top.line).Time += elapsed
+	p.sample().time += elapsed
+}
+
+func (p *Profiler) sample() *sample {
+	var key strings.Builder
+	for _, a := range p.stack {
+		fmt.Fprintf(&key, "%p:%d;", a.function, a.line)
+	}
+	s, ok := p.samples[key.String()]
+	if !ok {
+		s = &sample{stack: append([]activation(nil), p.stack...)}
+		p.samples[key.String()] = s
+	}
+	return s
+}
+
+func (p *Profiler) function(node *ast.FuncDeclaration) *FunctionStats {
+	function, ok := p.Functions[node]
+	if !ok {
+		function = &FunctionStats{
+			Name: node.Name.Lexeme,
+			Line: node.Name.Line,
+		}
+		p.Functions[node] = function
+	}
+	return function
+}
+
+func (p *Profiler) lineStats(line int) *LineStats {
+	stats, ok := p.LineStats[line]
+	if !ok {
+		stats = &LineStats{}
+		p.LineStats[line] = stats
+	}
+	return stats
+}
+
+// SortedFunctions returns the script and every called function, the most
+// expensive first.
+func (p *Profiler) SortedFunctions() []*FunctionStats {
+	functions := []*FunctionStats{p.script}
+	for _, function := range p.Functions {
+		functions = append(functions, function)
+	}
+	sort.SliceStable(functions, func(i, j int) bool {
+		if functions[i].Exclusive != functions[j].Exclusive {
+			return functions[i].Exclusive > functions[j].Exclusive
+		}
+		return functions[i].Line < functions[j].Line
+	})
+	return functions
+}
+
+// WriteTable writes the function and line totals in a human readable form.
+func (p *Profiler) WriteTable(w io.Writer, source string) {
+	fmt.Fprintf(w, "Total time %v\n\n", p.elapsed)
+	fmt.Fprintf(w, "%-24s %8s %14s %14s\n", "Function", "Calls", "Inclusive", "Exclusive")
+	for _, function := range p.SortedFunctions() {
+		name := fmt.Sprintf("%s:%d", function.Name, function.Line)
+		fmt.Fprintf(w, "%-24s %8d %14v %14v\n", name, function.Calls, function.Inclusive, function.Exclusive)
+	}
+
+	var lines []int
+	for line := range p.LineStats {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	sourceLines := strings.Split(source, "\n")
+	fmt.Fprintf(w, "\n%6s %10s %14s  %s\n", "Line", "Hits", "Time", "Source")
+	for _, line := range lines {
+		text := ""
+		if line >= 1 && line <= len(sourceLines) {
+			text = strings.TrimSpace(sourceLines[line-1])
+		}
+		stats := p.LineStats[line]
+		fmt.Fprintf(w, "%6d %10d %14v  %s\n", line, stats.Hits, stats.Time, text)
+	}
+}
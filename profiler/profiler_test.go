@@ -0,0 +1,60 @@
+package profiler_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/profiler"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestProfiler(t *testing.T) {
+	g := &glox.Glox{}
+	script, err := g.Compile(`fun fib(n) {
+  if (n <= 1) return n;
+  return fib(n - 2) + fib(n - 1);
+}
+print fib(5);
+`)
+	assert.Equal(t, err, nil)
+	interpreter := g.NewInterpreter(script)
+	interpreter.Stdout = ioutil.Discard
+
+	// Every event advances the fake clock by one millisecond
+	now := time.Unix(0, 0)
+	p := profiler.NewProfiler("fib.lox", script.Lines)
+	p.Now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	interpreter.AddHook(p)
+	p.Start()
+	script.Node.Accept(interpreter)
+	p.Stop()
+
+	functions := p.SortedFunctions()
+	assert.Equal(t, functions[0].Name, "fib")
+	assert.Equal(t, functions[0].Calls, 15)
+	assert.Equal(t, functions[1].Name, "<script>")
+	assert.Equal(t, functions[0].Exclusive+functions[1].Exclusive, functions[1].Inclusive)
+	// The if and the return of the 8 base cases both start on line 2
+	assert.Equal(t, p.LineStats[2].Hits, 15+8)
+
+	var table bytes.Buffer
+	p.WriteTable(&table, "")
+	assert.Equal(t, strings.Contains(table.String(), "fib:1"), true)
+
+	var pprof bytes.Buffer
+	assert.Equal(t, p.WritePprof(&pprof), nil)
+	reader, err := gzip.NewReader(&pprof)
+	assert.Equal(t, err, nil)
+	data, err := ioutil.ReadAll(reader)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, bytes.Contains(data, []byte("fib.lox")), true)
+	assert.Equal(t, bytes.Contains(data, []byte("(script)")), true)
+}
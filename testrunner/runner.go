@@ -0,0 +1,210 @@
+package testrunner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/coverage"
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/utils"
+	"github.com/jameslahm/glox/visitor"
+)
+
+const (
+	testFileSuffix = "_test.lox"
+	testPrefix     = "test"
+)
+
+// Result is the outcome of one test function. Line is the line of the
+// failing assertion or runtime error.
+type Result struct {
+	File     string
+	Name     string
+	Passed   bool
+	Line     int
+	Message  string
+	Duration time.Duration
+}
+
+// Runner runs the test functions of Lox test files. Every test gets its own
+// interpreter that runs the top level of the file before calling the test,
+// so tests can't see each other's state.
+type Runner struct {
+	Glox    *glox.Glox
+	Output  io.Writer
+	Run     *regexp.Regexp
+	Verbose bool
+	// Coverage records the coverage of the tests when not nil
+	Coverage *coverage.Profile
+}
+
+func NewRunner(g *glox.Glox, output io.Writer) *Runner {
+	return &Runner{Glox: g, Output: output}
+}
+
+// Discover returns the test files in paths, directories are searched
+// recursively for files ending in _test.lox.
+func Discover(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(file, testFileSuffix) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Tests returns the top level functions of program whose names start
+// with test, in source order.
+func Tests(program ast.Node) []*ast.FuncDeclaration {
+	var tests []*ast.FuncDeclaration
+	if program, ok := program.(*ast.Program); ok {
+		for _, statement := range program.Statements {
+			if function, ok := statement.(*ast.FuncDeclaration); ok && strings.HasPrefix(function.Name.Lexeme, testPrefix) {
+				tests = append(tests, function)
+			}
+		}
+	}
+	return tests
+}
+
+// RunFiles runs the tests of every file and reports whether all passed.
+func (r *Runner) RunFiles(files []string) bool {
+	passed := true
+	for _, file := range files {
+		start := time.Now()
+		results, err := r.RunFile(file)
+		elapsed := time.Since(start)
+		if err != nil {
+			fmt.Fprintf(r.Output, "FAIL\t%s\t%v\n", file, err)
+			passed = false
+			continue
+		}
+		filePassed := true
+		for _, result := range results {
+			filePassed = filePassed && result.Passed
+		}
+		if filePassed {
+			fmt.Fprintf(r.Output, "ok  \t%s\t%.3fs\n", file, elapsed.Seconds())
+		} else {
+			fmt.Fprintf(r.Output, "FAIL\t%s\t%.3fs\n", file, elapsed.Seconds())
+			passed = false
+		}
+	}
+	if !passed {
+		fmt.Fprintln(r.Output, "FAIL")
+	}
+	return passed
+}
+
+// RunFile runs the tests of one file, reporting each test as it finishes.
+func (r *Runner) RunFile(path string) ([]*Result, error) {
+	script, err := r.Glox.CompileFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cover *coverage.Coverage
+	if r.Coverage != nil {
+		cover = coverage.NewCoverage(path, script.Lines, script.Branches, script.Columns)
+	}
+
+	var results []*Result
+	for _, test := range Tests(script.Node) {
+		name := test.Name.Lexeme
+		if r.Run != nil && !r.Run.MatchString(name) {
+			continue
+		}
+		if r.Verbose {
+			fmt.Fprintf(r.Output, "=== RUN   %s\n", name)
+		}
+		result := r.runTest(script, test, cover)
+		result.File = path
+		r.report(result)
+		results = append(results, result)
+	}
+	if cover != nil {
+		r.Coverage.Merge(cover.Profile())
+	}
+	return results, nil
+}
+
+func (r *Runner) runTest(script *glox.Script, test *ast.FuncDeclaration, cover *coverage.Coverage) *Result {
+	result := &Result{Name: test.Name.Lexeme}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	if len(test.Params) != 0 {
+		result.Line = test.Name.Line
+		result.Message = utils.TEST_FUNCTION_WITH_PARAMS
+		return result
+	}
+
+	interpreter := r.Glox.NewInterpreter(script)
+	if cover != nil {
+		interpreter.AddHook(cover)
+	}
+	err := interpreter.Interpret(script.Node)
+	if exit, ok := err.(*visitor.Exit); ok {
+		// Even exit(0) fails the test, which never ran
+		result.Line = exit.Token.Line
+		result.Message = fmt.Sprintf(utils.TEST_FILE_EXITED, exit.Code)
+		return result
+	}
+	if err == nil {
+		// Call the test the way Lox code would, so the failing line of an
+		// assertion in the test itself is known
+		err = interpreter.Interpret(&ast.CallExpr{
+			Callee: &ast.Variable{Name: test.Name},
+			Paren:  test.Name,
+		})
+	}
+	if runtimeError, ok := err.(*glox_error.RuntimeError); ok {
+		result.Line = runtimeError.Token().Line
+		result.Message = runtimeError.Message()
+		return result
+	}
+	if exit, ok := err.(*visitor.Exit); ok && exit.Code != 0 {
+		result.Line = exit.Token.Line
+		result.Message = fmt.Sprintf(utils.TEST_EXITED, exit.Code)
+		return result
+	}
+	result.Passed = true
+	return result
+}
+
+func (r *Runner) report(result *Result) {
+	if result.Passed {
+		if r.Verbose {
+			fmt.Fprintf(r.Output, "--- PASS: %s (%.2fs)\n", result.Name, result.Duration.Seconds())
+		}
+		return
+	}
+	fmt.Fprintf(r.Output, "--- FAIL: %s (%.2fs)\n", result.Name, result.Duration.Seconds())
+	fmt.Fprintf(r.Output, "    %s:%d: %s\n", result.File, result.Line, result.Message)
+}
@@ -0,0 +1,91 @@
+package testrunner_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/jameslahm/glox"
+	"github.com/jameslahm/glox/testrunner"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+const source = `var counter = 0;
+
+fun testFirst() {
+  counter = counter + 1;
+  assertEqual(counter, 1);
+}
+
+fun testSecond() {
+  counter = counter + 1;
+  assertEqual(counter, 1);
+}
+
+fun testFails() {
+  assertEqual("a" + "b", "abc");
+}
+
+fun testExits() {
+  exit(2);
+}
+
+fun helper() {
+  assert(false);
+}
+`
+
+func TestRunner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glox")
+	assert.Equal(t, err, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "counter_test.lox")
+	assert.Equal(t, ioutil.WriteFile(path, []byte(source), 0644), nil)
+	assert.Equal(t, ioutil.WriteFile(filepath.Join(dir, "counter.lox"), []byte(source), 0644), nil)
+
+	files, err := testrunner.Discover([]string{dir})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, files, []string{path})
+
+	var output bytes.Buffer
+	runner := testrunner.NewRunner(&glox.Glox{}, &output)
+	results, err := runner.RunFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(results), 4)
+	// Every test starts from a fresh interpreter
+	assert.Equal(t, results[0].Passed, true)
+	assert.Equal(t, results[1].Passed, true)
+	assert.Equal(t, results[2].Passed, false)
+	assert.Equal(t, results[2].Line, 14)
+	assert.Equal(t, results[2].Message, `Expected "abc" but got "ab"`)
+	assert.Equal(t, results[3].Passed, false)
+	assert.Equal(t, results[3].Line, 18)
+	assert.Equal(t, results[3].Message, "Test exited with status 2")
+
+	runner.Run = regexp.MustCompile("First|Second")
+	assert.Equal(t, runner.RunFiles(files), true)
+	runner.Run = nil
+	assert.Equal(t, runner.RunFiles(files), false)
+}
+
+func TestTopLevelExit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glox")
+	assert.Equal(t, err, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "exit_test.lox")
+	source := "exit(0);\n\nfun testNeverRuns() {\n  assert(false);\n}\n"
+	assert.Equal(t, ioutil.WriteFile(path, []byte(source), 0644), nil)
+
+	var output bytes.Buffer
+	runner := testrunner.NewRunner(&glox.Glox{}, &output)
+	results, err := runner.RunFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Passed, false)
+	assert.Equal(t, results[0].Line, 1)
+	assert.Equal(t, results[0].Message, "Test file exited with status 0 before the test ran")
+	assert.Equal(t, runner.RunFiles([]string{path}), false)
+}
@@ -1,13 +1,23 @@
 package utils
 
-func IsDigit(c byte) bool {
+import "unicode"
+
+func IsDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 
-func IsAlpha(c byte) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+// IsAlpha reports whether c can start an identifier, identifiers start with
+// a Unicode letter or '_'.
+func IsAlpha(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+// IsAlphaDigit reports whether c can continue an identifier, which also
+// allows Unicode digits and combining marks.
+func IsAlphaDigit(c rune) bool {
+	return IsAlpha(c) || unicode.IsDigit(c) || unicode.IsMark(c)
 }
 
-func IsAlphaDigit(c byte) bool {
-	return IsDigit(c) || IsAlpha(c)
+func IsHexDigit(c rune) bool {
+	return IsDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
 }
@@ -2,10 +2,20 @@ package utils
 
 const UNEXPECTED_CHARACTER_MESSAGE = "Unexpected character"
 const UNTERMINATED_STRING = "Unterminated string"
+const UNTERMINATED_COMMENT = "Unterminated block comment"
+const INVALID_UTF8 = "Invalid UTF-8 encoding"
+const UNTERMINATED_INTERPOLATION = "Unterminated string interpolation"
+const EMPTY_INTERPOLATION = "Expect expression in interpolation"
+const INVALID_ESCAPE = "Invalid escape sequence \\%c"
+const INVALID_UNICODE_ESCAPE = "Invalid unicode escape sequence"
 const INVALID_NUMBER = "Invalid number"
 const UNMATCHED_PAREN = "Unmatched paren"
 const INVALID_OPERAND_NUMBER = "Operand must be a number"
 const INVALID_OPERAND_NUMBERS = "Operands must be numbers"
+const INVALID_PLUS_OPERANDS = "Operands must be two numbers or two strings"
+const INVALID_OPERAND_INTEGER = "Operand must be an integer"
+const INVALID_OPERAND_INTEGERS = "Operands must be integers"
+const NEGATIVE_SHIFT_COUNT = "Shift count can't be negative"
 const EXPECT_SEMICOLON_AFTER_VALUE = "Expect ';' after value"
 const EXPECT_VARIABLE_NAME = "Expect variable name"
 const EXPECT_SEMICOLON_AFTER_VARIABLE_DECLARATION = "Expect ';' after variable declaration"
@@ -17,6 +27,12 @@ const EXPECT_LEFT_PAREN_AFTER_WHILE = "Expect '(' after while"
 const EXPECT_RIGHT_PAREN_AFTER_CONDITION = "Expect ')' after condition"
 const EXPECT_LEFT_PAREN_AFTER_FOR = "Expect '(' after for"
 const EXPECT_SEMICOLON_AFTER_LOOP_CONDITION = "Expect ';' after loop condition"
+const EXPECT_LEFT_BRACE_AFTER_TRY = "Expect '{' after try"
+const EXPECT_CATCH_AFTER_TRY = "Expect catch after try block"
+const EXPECT_LEFT_PAREN_AFTER_CATCH = "Expect '(' after catch"
+const EXPECT_CATCH_VARIABLE_NAME = "Expect catch variable name"
+const EXPECT_RIGHT_PAREN_AFTER_CATCH_VARIABLE = "Expect ')' after catch variable"
+const EXPECT_LEFT_BRACE_AFTER_CATCH = "Expect '{' after catch variable"
 const EXPECT_RIGHT_PAREN_AFTER_CLAUSES = "Expect ')' after clauses"
 const EXPECT_RIGHT_PAREN_AFTER_ARGUMENTS = "Expect ')' after arguments"
 const WARN_NO_MORE_THAN_MAXIMUM_ARGUMENTS = "Can't have more than 255 arguments"
@@ -32,6 +48,9 @@ const WARN_RETURN_FROM_NOFUNCTION = "Can't return from no function"
 const EXPECT_CLASS_NAME = "Expect class name"
 const EXPECT_LEFT_BRACE_BEFORE_CLASS_BODY = "Expect '{' before class body"
 const EXPECT_RIGHT_BRACE_AFTER_CLASS_BODY = "Expect '}' after class body"
+const EXPECT_TRAIT_NAME = "Expect trait name"
+const EXPECT_LEFT_BRACE_BEFORE_TRAIT_BODY = "Expect '{' before trait body"
+const EXPECT_RIGHT_BRACE_AFTER_TRAIT_BODY = "Expect '}' after trait body"
 const EXPECT_PROPERTY_NAME_AFTER_DOT = "Expect property name after '.'"
 const ONLY_INSTANCES_HAVE_PROPERTIES = "Only instance have properties"
 const WARN_USE_THIS_OUT_CLASS = "Can't use 'this' outside of a class"
@@ -43,7 +62,58 @@ const EXPECT_DOT_AFTER_SUPER = "Expect '.' after super"
 const EXPECT_SUPER_METHOD_NAME = "Expect super class method name"
 const WARN_USE_SUPER_OUT_CLASS = "Can't use 'super' outside a class"
 const WARN_USE_SUPER_OUT_SUBCLASS = "Can't use 'super' in a class with no super class"
+const WARN_USE_THIS_IN_CLASS_METHOD = "Can't use 'this' in a class method"
+const WARN_USE_SUPER_IN_CLASS_METHOD = "Can't use 'super' in a class method"
+const WARN_USE_SUPER_IN_TRAIT = "Can't use 'super' in a trait"
+const WARN_INIT_IN_TRAIT = "A trait can't have an initializer"
+const EXPECT_EXPRESSION = "Expect expression"
+const EXPECT_END_OF_EXPRESSION = "Expect end of expression"
+const EXPECT_TYPE_NAME = "Expect type name"
+const EXPECT_FIELD_NAME = "Expect field name"
+const CONST_WITHOUT_INITIALIZER = "Expect '=' after constant name"
+const WARN_PRIVATE_OUTSIDE_THIS = "Private property %s can only be accessed through 'this'"
+const WARN_UNDECLARED_PRIVATE = "Private property %s isn't declared in this class"
+const EXPECT_SEMICOLON_AFTER_FIELD_DECLARATION = "Expect ';' after field declaration"
+const SETTER_PARAMETER = "A setter must have exactly one parameter"
+const EXPECT_RIGHT_BRACE_AFTER_INTERPOLATION = "Expect '}' after interpolation"
+const EXPECT_RIGHT_BRACKET_AFTER_INDEX = "Expect ']' after index"
+const EXPECT_COLON_IN_CONDITIONAL = "Expect ':' after then branch of conditional expression"
 
 const UNDEFINED_VARIABLE = "Undefined variable %s\n"
 const MISMATCH_CALL_PARAMS_LENGTH = "Expected %d arguments but got %d\n"
 const UNDEFINED_PROPERTY = "Undefined property %s\n"
+const NO_SETTER = "Property %s has a getter but no setter"
+const MUST_BE_TRAIT = "Can only mix in traits"
+const TRAIT_CONFLICT = "Method %s is defined by both trait %s and trait %s"
+const ASSIGN_TO_CONSTANT = "Can't assign to constant %s"
+const ASSIGN_TO_CONST_FIELD = "Can't assign to const field %s"
+const UNDECLARED_FIELD = "Undeclared field %s of %s"
+const PRIVATE_PROPERTY = "Can't access private property %s"
+const ASSERTION_FAILED = "Assertion failed"
+const ASSERT_EQUAL_FAILED = "Expected %s but got %s"
+const INVALID_LEN_ARGUMENT = "Can only take the length of a string, a list or a map"
+const INVALID_ARGUMENT = "Argument %d of %s must be %s"
+const INDEX_OUT_OF_RANGE = "Index out of range"
+const STRING_TOO_LONG = "String too long"
+const INVALID_RANDOM_RANGE = "Empty range for math.randomInt"
+const INVALID_JSON = "Invalid JSON at line %d, column %d: %s"
+const INVALID_JSON_VALUE = "Can't convert %s to JSON"
+const CYCLIC_JSON_VALUE = "Can't convert a cyclic value to JSON"
+const INVALID_MAP_KEY = "Map keys must be strings"
+const INVALID_TIME = "Can't parse %q as a time with layout %q"
+const INVALID_REGEX = "Invalid regular expression: %v"
+const FILE_SYSTEM_DISABLED = "%s: file system access is disabled"
+const INVALID_INDEX = "Index must be an integer"
+const ONLY_INDEX_STRINGS_AND_LISTS = "Can only index strings, lists and maps"
+const TEST_FUNCTION_WITH_PARAMS = "Test functions can't take parameters"
+const TEST_EXITED = "Test exited with status %d"
+const TEST_FILE_EXITED = "Test file exited with status %d before the test ran"
+
+const UNKNOWN_TYPE = "Unknown type %s"
+const TYPE_MISMATCH = "Type mismatch: expected %s but got %s"
+const INVALID_OPERAND_TYPE = "Operand of '%s' must be %s but got %s"
+const INVALID_OPERAND_TYPES = "Operands of '%s' must be %s but got %s and %s"
+const INVALID_ARGUMENT_TYPE = "Argument %d of %s: expected %s but got %s"
+const INVALID_RETURN_TYPE = "Return type mismatch: expected %s but got %s"
+const INVALID_FIELD_TYPE = "Field %s: expected %s but got %s"
+const NOT_CALLABLE_TYPE = "Can't call a value of type %s"
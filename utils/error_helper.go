@@ -1,7 +1,25 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Output receives the diagnostics of the lexer, parser, resolver and
+// interpreter.
+var Output io.Writer = os.Stderr
 
 func Error(line int, message string) {
-	fmt.Printf("[line %d]  Error: %s\n", line, message)
+	fmt.Fprintln(Output, FormatError(line, "", message))
+}
+
+// FormatError formats a compile error, where tells the token the error is
+// at, such as " at 'x'" or " at end", and may be empty.
+func FormatError(line int, where string, message string) string {
+	return fmt.Sprintf("[line %d] Error%s: %s", line, where, message)
+}
+
+func Report(err error) {
+	fmt.Fprintln(Output, err)
 }
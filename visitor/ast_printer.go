@@ -31,6 +31,18 @@ func (v *AstPrinter) VisitGroupExpr(node *ast.GroupExpr) interface{} {
 	return v.Parenthesize("group", node.Expr)
 }
 
+func (v *AstPrinter) VisitIndexExpr(node *ast.IndexExpr) interface{} {
+	return v.Parenthesize("[]", node.Expr, node.Index)
+}
+
+func (v *AstPrinter) VisitConditionalExpr(node *ast.ConditionalExpr) interface{} {
+	return v.Parenthesize("?:", node.Condition, node.Then, node.Else)
+}
+
+func (v *AstPrinter) VisitInterpolationExpr(node *ast.InterpolationExpr) interface{} {
+	return v.Parenthesize("interpolate", node.Parts...)
+}
+
 func (v *AstPrinter) Parenthesize(lexeme string, exprs ...ast.Node) string {
 	var sb strings.Builder
 	sb.WriteString("(")
@@ -0,0 +1,24 @@
+package visitor
+
+import (
+	"github.com/jameslahm/glox/environment"
+	"github.com/jameslahm/glox/lexer"
+)
+
+// CallFrame is an active call of a LoxFunction.
+type CallFrame struct {
+	Function *LoxFunction
+	// CallSite is the closing paren of the call expression
+	CallSite lexer.Token
+	// CallerEnv is the environment the call was made from
+	CallerEnv *environment.Env
+}
+
+func (frame *CallFrame) Name() string {
+	return frame.Function.Node.Name.Lexeme
+}
+
+// ReturnValue unwinds a function body from a return statement.
+type ReturnValue struct {
+	Value interface{}
+}
@@ -63,6 +63,10 @@ func (v *DefaultVisitor) VisitWhileStatement(node *ast.WhileStatement) interface
 	return nil
 }
 
+func (v *DefaultVisitor) VisitTryStatement(node *ast.TryStatement) interface{} {
+	return nil
+}
+
 func (v *DefaultVisitor) VisitCallExpr(node *ast.CallExpr) interface{} {
 	return nil
 }
@@ -87,6 +91,10 @@ func (v *DefaultVisitor) VisitClassDeclaration(node *ast.ClassDeclaration) inter
 	return nil
 }
 
+func (v *DefaultVisitor) VisitTraitDeclaration(node *ast.TraitDeclaration) interface{} {
+	return nil
+}
+
 func (v *DefaultVisitor) VisitGetExpr(node *ast.GetExpr) interface{} {
 	return nil
 }
@@ -102,3 +110,15 @@ func (v *DefaultVisitor) VisitThisExpr(node *ast.ThisExpr) interface{} {
 func (v *DefaultVisitor) VisitSuperExpr(node *ast.SuperExpr) interface{} {
 	return nil
 }
+
+func (v *DefaultVisitor) VisitInterpolationExpr(node *ast.InterpolationExpr) interface{} {
+	return nil
+}
+
+func (v *DefaultVisitor) VisitConditionalExpr(node *ast.ConditionalExpr) interface{} {
+	return nil
+}
+
+func (v *DefaultVisitor) VisitIndexExpr(node *ast.IndexExpr) interface{} {
+	return nil
+}
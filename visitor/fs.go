@@ -0,0 +1,121 @@
+package visitor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/utils"
+)
+
+// FS is the fs module. Its functions only work when the interpreter allows
+// FileSystem access, failures of the underlying calls are runtime errors.
+var FS = &LoxModule{
+	Name: "fs",
+	Members: map[string]interface{}{
+		"readFile": fsFunction("readFile", 1, func(v *AstInterpreter, arguments []interface{}) (interface{}, error) {
+			content, err := ioutil.ReadFile(v.StringArgument("fs.readFile", arguments, 0))
+			return string(content), err
+		}),
+		"writeFile": fsFunction("writeFile", 2, func(v *AstInterpreter, arguments []interface{}) (interface{}, error) {
+			path := v.StringArgument("fs.writeFile", arguments, 0)
+			content := v.StringArgument("fs.writeFile", arguments, 1)
+			return nil, ioutil.WriteFile(path, []byte(content), 0644)
+		}),
+		"appendFile": fsFunction("appendFile", 2, func(v *AstInterpreter, arguments []interface{}) (interface{}, error) {
+			path := v.StringArgument("fs.appendFile", arguments, 0)
+			content := v.StringArgument("fs.appendFile", arguments, 1)
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := file.WriteString(content); err != nil {
+				file.Close()
+				return nil, err
+			}
+			return nil, file.Close()
+		}),
+		"exists": fsFunction("exists", 1, func(v *AstInterpreter, arguments []interface{}) (interface{}, error) {
+			_, err := os.Stat(v.StringArgument("fs.exists", arguments, 0))
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return err == nil, err
+		}),
+		// listDir returns the sorted names of the entries of a directory
+		"listDir": fsFunction("listDir", 1, func(v *AstInterpreter, arguments []interface{}) (interface{}, error) {
+			infos, err := ioutil.ReadDir(v.StringArgument("fs.listDir", arguments, 0))
+			if err != nil {
+				return nil, err
+			}
+			var names []interface{}
+			for _, info := range infos {
+				names = append(names, info.Name())
+			}
+			return NewLoxList(names), nil
+		}),
+		"remove": fsFunction("remove", 1, func(v *AstInterpreter, arguments []interface{}) (interface{}, error) {
+			return nil, os.Remove(v.StringArgument("fs.remove", arguments, 0))
+		}),
+		// mkdir creates a directory along with any missing parents
+		"mkdir": fsFunction("mkdir", 1, func(v *AstInterpreter, arguments []interface{}) (interface{}, error) {
+			return nil, os.MkdirAll(v.StringArgument("fs.mkdir", arguments, 0), 0755)
+		}),
+	},
+}
+
+func fsFunction(name string, params int, function func(v *AstInterpreter, arguments []interface{}) (interface{}, error)) *NativeFunction {
+	name = "fs." + name
+	return &NativeFunction{
+		Name:   name,
+		Params: params,
+		Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+			if !v.FileSystem {
+				panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.FILE_SYSTEM_DISABLED, name), v.CallSite()))
+			}
+			result, err := function(v, arguments)
+			if err != nil {
+				panic(glox_error.NewRuntimeError(fmt.Sprintf("%s: %v", name, err), v.CallSite()))
+			}
+			return result
+		},
+	}
+}
+
+// Path is the path module, its functions only compute paths and never
+// access the file system.
+var Path = &LoxModule{
+	Name: "path",
+	Members: map[string]interface{}{
+		"join": &NativeFunction{
+			Name:   "path.join",
+			Params: 2,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				return filepath.Join(v.StringArgument("path.join", arguments, 0), v.StringArgument("path.join", arguments, 1))
+			},
+		},
+		"base": pathFunction("base", filepath.Base),
+		"dir":  pathFunction("dir", filepath.Dir),
+		"ext":  pathFunction("ext", filepath.Ext),
+		"isAbs": &NativeFunction{
+			Name:   "path.isAbs",
+			Params: 1,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				return filepath.IsAbs(v.StringArgument("path.isAbs", arguments, 0))
+			},
+		},
+	},
+}
+
+func pathFunction(name string, function func(string) string) *NativeFunction {
+	name = "path." + name
+	return &NativeFunction{
+		Name:   name,
+		Params: 1,
+		Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+			return function(v.StringArgument(name, arguments, 0))
+		},
+	}
+}
@@ -1,18 +1,142 @@
 package visitor
 
 import (
-	"time"
+	"fmt"
+	"unicode/utf8"
 
-	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/utils"
 )
 
-type Clock struct {
+// NativeFunction is a function implemented in Go. Errors are raised by
+// panicking with a RuntimeError at the call site, see CallSite.
+type NativeFunction struct {
+	Name     string
+	Params   int
+	Function func(v *AstInterpreter, arguments []interface{}) interface{}
 }
 
-func (c *Clock) Call(v ast.Visitor, arguments []interface{}) interface{} {
-	return time.Now().Unix()
+func (f *NativeFunction) Call(v *AstInterpreter, arguments []interface{}) interface{} {
+	return f.Function(v, arguments)
 }
 
-func (c *Clock) Arity() int {
-	return 0
+func (f *NativeFunction) Arity() int {
+	return f.Params
+}
+
+// Assert fails unless its argument is truthy.
+var Assert = &NativeFunction{
+	Name:   "assert",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		if !IsTruthy(arguments[0]) {
+			panic(glox_error.NewRuntimeError(utils.ASSERTION_FAILED, v.CallSite()))
+		}
+		return nil
+	},
+}
+
+// AssertEqual fails unless its arguments, the actual and the expected value,
+// are equal.
+var AssertEqual = &NativeFunction{
+	Name:   "assertEqual",
+	Params: 2,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		actual, expected := arguments[0], arguments[1]
+		if !IsEqual(actual, expected) {
+			message := fmt.Sprintf(utils.ASSERT_EQUAL_FAILED, Inspect(expected), Inspect(actual))
+			panic(glox_error.NewRuntimeError(message, v.CallSite()))
+		}
+		return nil
+	},
+}
+
+// Len returns the length of a list or a map, or of a string in Unicode code
+// points.
+var Len = &NativeFunction{
+	Name:   "len",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		switch value := arguments[0].(type) {
+		case string:
+			return int64(utf8.RuneCountInString(value))
+		case *LoxList:
+			return int64(len(value.Elements))
+		case *LoxMap:
+			return int64(len(value.Keys))
+		}
+		panic(glox_error.NewRuntimeError(utils.INVALID_LEN_ARGUMENT, v.CallSite()))
+	},
+}
+
+// StringArgument returns the argument at index of a native function, which
+// must be a string.
+func (v *AstInterpreter) StringArgument(function string, arguments []interface{}, index int) string {
+	s, ok := arguments[index].(string)
+	if !ok {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_ARGUMENT, index+1, function, "a string"), v.CallSite()))
+	}
+	return s
+}
+
+// IntegerArgument returns the argument at index of a native function, which
+// must be a number with an integer value.
+func (v *AstInterpreter) IntegerArgument(function string, arguments []interface{}, index int) int64 {
+	i, ok := ToInteger(arguments[index])
+	if !ok {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_ARGUMENT, index+1, function, "an integer"), v.CallSite()))
+	}
+	return i
+}
+
+// NumberArgument returns the argument at index of a native function, which
+// must be a number.
+func (v *AstInterpreter) NumberArgument(function string, arguments []interface{}, index int) interface{} {
+	if !IsNumber(arguments[index]) {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_ARGUMENT, index+1, function, "a number"), v.CallSite()))
+	}
+	return arguments[index]
+}
+
+// InstanceArgument returns the argument at index of a native function, which
+// must be an instance.
+func (v *AstInterpreter) InstanceArgument(function string, arguments []interface{}, index int) *LoxInstance {
+	instance, ok := arguments[index].(*LoxInstance)
+	if !ok {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_ARGUMENT, index+1, function, "an instance"), v.CallSite()))
+	}
+	return instance
+}
+
+// ClassArgument returns the argument at index of a native function, which
+// must be a class.
+func (v *AstInterpreter) ClassArgument(function string, arguments []interface{}, index int) *LoxClass {
+	class, ok := arguments[index].(*LoxClass)
+	if !ok {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_ARGUMENT, index+1, function, "a class"), v.CallSite()))
+	}
+	return class
+}
+
+// boundMethod is a native method of the values of a built in type, such as
+// strings, function receives the value the method was read from.
+type boundMethod struct {
+	params   int
+	function func(v *AstInterpreter, receiver interface{}, arguments []interface{}) interface{}
+}
+
+// bindMethod returns the method name of table bound to receiver.
+func bindMethod(receiver interface{}, table map[string]*boundMethod, name lexer.Token) *NativeFunction {
+	method, ok := table[name.Lexeme]
+	if !ok {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.UNDEFINED_PROPERTY, name.Lexeme), name))
+	}
+	return &NativeFunction{
+		Name:   name.Lexeme,
+		Params: method.params,
+		Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+			return method.function(v, receiver, arguments)
+		},
+	}
 }
@@ -1,30 +1,110 @@
 package visitor
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/jameslahm/glox/ast"
 	"github.com/jameslahm/glox/environment"
 	"github.com/jameslahm/glox/glox_error"
 	"github.com/jameslahm/glox/lexer"
 	"github.com/jameslahm/glox/utils"
-	"github.com/spf13/cast"
 )
 
+// StatementHook is notified before the interpreter executes a statement,
+// tools such as the debugger are built on it.
+type StatementHook interface {
+	BeforeStatement(node ast.Node, env *environment.Env)
+}
+
+// CallHook may be implemented by a StatementHook to also be notified when a
+// Lox function is entered and left.
+type CallHook interface {
+	EnterFunction(frame *CallFrame)
+	ExitFunction(frame *CallFrame)
+}
+
+// BranchHook may be implemented by a StatementHook to also be notified of
+// the outcome of every if, loop condition, conditional expression and
+// logical operator.
+type BranchHook interface {
+	Branch(node ast.Node, taken bool)
+}
+
 type AstInterpreter struct {
 	// DefaultVisitor
 	Env                  *environment.Env
+	Globals              *environment.Env
 	_originEnvStack      []*environment.Env
 	VariableBindDistance map[ast.Node]int
+
+	Hooks  []StatementHook
+	Frames []*CallFrame
+
+	// Stdout receives the output of print statements
+	Stdout io.Writer
+	// Stdin is read by input and readLine
+	Stdin *bufio.Reader
+	// Args are the arguments given to the script, returned by args
+	Args []string
+	// FileSystem allows the functions of the fs module to access files
+	FileSystem bool
+	// Time is the source of clock, now and the time module
+	Time TimeSource
+	// Random is the source of math.random, seeded from the time until
+	// math.seed is called
+	Random *rand.Rand
+
+	// regexes caches the regexes compiled by Regex by pattern
+	regexes map[string]*LoxRegex
+
+	// callSite is the paren of the call expression being evaluated
+	callSite lexer.Token
 }
 
 func NewAstInterpreter(variableBindDistances map[ast.Node]int) *AstInterpreter {
+	globals := environment.NewEnvironment(nil)
 	interpreter := &AstInterpreter{
-		Env:                  environment.NewEnvironment(nil),
+		Env:                  globals,
+		Globals:              globals,
 		VariableBindDistance: variableBindDistances,
+		Stdout:               os.Stdout,
+		Stdin:                bufio.NewReader(os.Stdin),
+		Time:                 SystemTime,
+		Random:               rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 
-	interpreter.Env.Define("clock", &Clock{})
+	interpreter.Env.Define(Clock.Name, Clock)
+	interpreter.Env.Define(Now.Name, Now)
+	interpreter.Env.Define(Sleep.Name, Sleep)
+	interpreter.Env.Define(Time.Name, Time)
+	interpreter.Env.Define(Assert.Name, Assert)
+	interpreter.Env.Define(AssertEqual.Name, AssertEqual)
+	interpreter.Env.Define(Len.Name, Len)
+	interpreter.Env.Define(Math.Name, Math)
+	interpreter.Env.Define(FS.Name, FS)
+	interpreter.Env.Define(Path.Name, Path)
+	interpreter.Env.Define(JSON.Name, JSON)
+	interpreter.Env.Define(Regex.Name, Regex)
+	interpreter.Env.Define(Type.Name, Type)
+	interpreter.Env.Define(ClassName.Name, ClassName)
+	interpreter.Env.Define(HasField.Name, HasField)
+	interpreter.Env.Define(GetField.Name, GetField)
+	interpreter.Env.Define(SetField.Name, SetField)
+	interpreter.Env.Define(Fields.Name, Fields)
+	interpreter.Env.Define(Methods.Name, Methods)
+	interpreter.Env.Define(Superclass.Name, Superclass)
+	interpreter.Env.Define(InstanceOf.Name, InstanceOf)
+	interpreter.Env.Define(Input.Name, Input)
+	interpreter.Env.Define(ReadLine.Name, ReadLine)
+	interpreter.Env.Define(Args.Name, Args)
+	interpreter.Env.Define(Env.Name, Env)
+	interpreter.Env.Define(ExitFunction.Name, ExitFunction)
 
 	return interpreter
 }
@@ -32,39 +112,40 @@ func NewAstInterpreter(variableBindDistances map[ast.Node]int) *AstInterpreter {
 func (v *AstInterpreter) VisitBinaryExpr(node *ast.BinaryExpr) interface{} {
 	leftValue := node.Left.Accept(v)
 	rightValue := node.Right.Accept(v)
+	return v.Binary(node.Operator, leftValue, rightValue)
+}
 
-	switch node.Operator.Type {
-	case lexer.MINUS:
-		v.CheckNumberOperands(node.Operator, leftValue, rightValue)
-		return cast.ToFloat64(leftValue) - cast.ToFloat64(rightValue)
-	case lexer.SLASH:
-		v.CheckNumberOperands(node.Operator, leftValue, rightValue)
-		return cast.ToFloat64(leftValue) / cast.ToFloat64(rightValue)
-	case lexer.STAR:
-		v.CheckNumberOperands(node.Operator, leftValue, rightValue)
-		return cast.ToFloat64(leftValue) * cast.ToFloat64(rightValue)
+// Binary applies a binary operator, it is shared by binary expressions and
+// compound assignments.
+func (v *AstInterpreter) Binary(operator lexer.Token, leftValue interface{}, rightValue interface{}) interface{} {
+	switch operator.Type {
+	case lexer.MINUS, lexer.SLASH, lexer.STAR, lexer.PERCENT, lexer.TILDE_SLASH, lexer.STAR_STAR:
+		v.CheckNumberOperands(operator, leftValue, rightValue)
+		return Arithmetic(operator.Type, leftValue, rightValue)
 	case lexer.PLUS:
-		if _, ok := leftValue.(float64); ok {
-			return cast.ToFloat64(leftValue) + cast.ToFloat64(rightValue)
-		} else {
-			return cast.ToString(leftValue) + cast.ToString(rightValue)
+		if IsNumber(leftValue) && IsNumber(rightValue) {
+			return Arithmetic(operator.Type, leftValue, rightValue)
+		}
+		leftString, leftOk := leftValue.(string)
+		rightString, rightOk := rightValue.(string)
+		if leftOk && rightOk {
+			return leftString + rightString
 		}
-	case lexer.GREATER:
-		v.CheckNumberOperands(node.Operator, leftValue, rightValue)
-		return cast.ToFloat64(leftValue) > cast.ToFloat64(rightValue)
-	case lexer.GREATER_EQUAL:
-		v.CheckNumberOperands(node.Operator, leftValue, rightValue)
-		return cast.ToFloat64(leftValue) >= cast.ToFloat64(rightValue)
-	case lexer.LESS:
-		v.CheckNumberOperands(node.Operator, leftValue, rightValue)
-		return cast.ToFloat64(leftValue) < cast.ToFloat64(rightValue)
-	case lexer.LESS_EQUAL:
-		v.CheckNumberOperands(node.Operator, leftValue, rightValue)
-		return cast.ToFloat64(leftValue) <= cast.ToFloat64(rightValue)
+		panic(glox_error.NewRuntimeError(utils.INVALID_PLUS_OPERANDS, operator))
+	case lexer.AMPERSAND, lexer.PIPE, lexer.CARET, lexer.LESS_LESS, lexer.GREATER_GREATER:
+		v.CheckNumberOperands(operator, leftValue, rightValue)
+		left, right := v.CheckIntegerOperands(operator, leftValue, rightValue)
+		if (operator.Type == lexer.LESS_LESS || operator.Type == lexer.GREATER_GREATER) && right < 0 {
+			panic(glox_error.NewRuntimeError(utils.NEGATIVE_SHIFT_COUNT, operator))
+		}
+		return Bitwise(operator.Type, left, right)
+	case lexer.GREATER, lexer.GREATER_EQUAL, lexer.LESS, lexer.LESS_EQUAL:
+		v.CheckNumberOperands(operator, leftValue, rightValue)
+		return Compare(operator.Type, leftValue, rightValue)
 	case lexer.BANG_EQUAL:
-		return leftValue != rightValue
+		return !IsEqual(leftValue, rightValue)
 	case lexer.EQUAL_EQUAL:
-		return leftValue == rightValue
+		return IsEqual(leftValue, rightValue)
 	default:
 		return nil
 	}
@@ -81,13 +162,28 @@ func (v *AstInterpreter) VisitReturnStatement(node *ast.ReturnStatement) interfa
 	if node.Expr != nil {
 		value = node.Expr.Accept(v)
 	}
-	panic(value)
+	panic(&ReturnValue{Value: value})
 }
 
 func (v *AstInterpreter) VisitAssignment(node *ast.Assignment) interface{} {
+	distance, local := v.VariableBindDistance[node]
+	var current interface{}
+	if node.Operator != nil {
+		if local {
+			current = v.Env.Get(node.Name, distance)
+		} else {
+			current = v.Globals.Get(node.Name, 0)
+		}
+	}
 	value := node.Expr.Accept(v)
-	distance := v.VariableBindDistance[node]
-	v.Env.Assign(node.Name, value, distance)
+	if node.Operator != nil {
+		value = v.Binary(*node.Operator, current, value)
+	}
+	if local {
+		v.Env.Assign(node.Name, value, distance)
+	} else {
+		v.Globals.Assign(node.Name, value, 0)
+	}
 	return value
 }
 
@@ -99,14 +195,29 @@ func (v *AstInterpreter) VisitGroupExpr(node *ast.GroupExpr) interface{} {
 	return node.Expr.Accept(v)
 }
 
+func (v *AstInterpreter) VisitInterpolationExpr(node *ast.InterpolationExpr) interface{} {
+	var sb strings.Builder
+	for _, part := range node.Parts {
+		sb.WriteString(Stringify(part.Accept(v)))
+	}
+	return sb.String()
+}
+
 func (v *AstInterpreter) VisitUnaryExpr(node *ast.UnaryExpr) interface{} {
 	value := node.Right.Accept(v)
 	switch node.Operator.Type {
 	case lexer.MINUS:
 		v.CheckNumberOperand(node.Operator, value)
-		return -cast.ToFloat64(value)
+		return Negate(value)
 	case lexer.BANG:
-		return !cast.ToBool(value)
+		return !IsTruthy(value)
+	case lexer.TILDE:
+		v.CheckNumberOperand(node.Operator, value)
+		integer, ok := ToInteger(value)
+		if !ok {
+			panic(glox_error.NewRuntimeError(utils.INVALID_OPERAND_INTEGER, node.Operator))
+		}
+		return ^integer
 	default:
 		return nil
 	}
@@ -118,16 +229,19 @@ func (v *AstInterpreter) VisitExprStatement(node *ast.ExprStatement) interface{}
 
 func (v *AstInterpreter) VisitPrintStatement(node *ast.PrintStatement) interface{} {
 	value := node.Node.Accept(v)
-	fmt.Println(value)
+	fmt.Fprintln(v.Stdout, Stringify(value))
 	return value
 }
 
 func (v *AstInterpreter) VisitVarDeclaration(node *ast.VarDeclaration) interface{} {
+	var value interface{}
 	if node.Expr != nil {
-		value := node.Expr.Accept(v)
-		v.Env.Define(node.Name.Lexeme, value)
+		value = node.Expr.Accept(v)
+	}
+	if node.Const {
+		v.Env.DefineConst(node.Name.Lexeme, value)
 	} else {
-		v.Env.Define(node.Name.Lexeme, nil)
+		v.Env.Define(node.Name.Lexeme, value)
 	}
 	return nil
 }
@@ -135,19 +249,18 @@ func (v *AstInterpreter) VisitVarDeclaration(node *ast.VarDeclaration) interface
 func (v *AstInterpreter) VisitVariable(node *ast.Variable) interface{} {
 	distance, ok := v.VariableBindDistance[node]
 	if !ok {
-		panic(glox_error.NewRuntimeError("No Distance", node.Name))
+		// Names the resolver didn't see declared are globals, such as the
+		// native functions
+		return v.Globals.Get(node.Name, 0)
 	}
 	return v.Env.Get(node.Name, distance)
 }
 
 func (v *AstInterpreter) VisitProgram(node *ast.Program) interface{} {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("%#v\n", r)
+	if err := v.Interpret(node); err != nil {
+		if _, ok := err.(*Exit); !ok {
+			fmt.Fprintln(utils.Output, err)
 		}
-	}()
-	for _, statement := range node.Statements {
-		statement.Accept(v)
 	}
 	return nil
 }
@@ -155,7 +268,7 @@ func (v *AstInterpreter) VisitProgram(node *ast.Program) interface{} {
 func (v *AstInterpreter) VisitBlockStatement(node *ast.BlockStatement) interface{} {
 	v.EnterScope()
 	for _, statement := range node.Statements {
-		statement.Accept(v)
+		v.Execute(statement)
 	}
 	v.ExitScope()
 	return nil
@@ -163,6 +276,11 @@ func (v *AstInterpreter) VisitBlockStatement(node *ast.BlockStatement) interface
 
 func (v *AstInterpreter) VisitCallExpr(node *ast.CallExpr) interface{} {
 	callee := node.Callee.Accept(v)
+	if _, ok := callee.(shortCircuit); ok {
+		// obj?.method() isn't called, nor are its arguments evaluated,
+		// when obj is nil
+		return endChain(node.ChainEnd, callee)
+	}
 
 	var arguments []interface{}
 	for _, param := range node.Arguments {
@@ -171,9 +289,11 @@ func (v *AstInterpreter) VisitCallExpr(node *ast.CallExpr) interface{} {
 	}
 	if f, ok := callee.(LoxCallable); ok {
 		if f.Arity() == len(node.Arguments) {
+			v.callSite = node.Paren
 			return f.Call(v, arguments)
 		} else {
-			panic(glox_error.NewRuntimeError(utils.MISMATCH_CALL_PARAMS_LENGTH, node.Paren))
+			message := fmt.Sprintf(utils.MISMATCH_CALL_PARAMS_LENGTH, f.Arity(), len(node.Arguments))
+			panic(glox_error.NewRuntimeError(message, node.Paren))
 		}
 	} else {
 		panic(glox_error.NewRuntimeError(utils.ONLY_CALL_FUNCTION_AND_CLASS, node.Paren))
@@ -181,12 +301,13 @@ func (v *AstInterpreter) VisitCallExpr(node *ast.CallExpr) interface{} {
 }
 
 func (v *AstInterpreter) VisitIfStatement(node *ast.IfStatement) interface{} {
-	value := cast.ToBool(node.Expr.Accept(v))
+	value := IsTruthy(node.Expr.Accept(v))
+	v.branch(node, value)
 	if value {
-		node.Then.Accept(v)
+		v.Execute(node.Then)
 	} else {
 		if node.Else != nil {
-			node.Else.Accept(v)
+			v.Execute(node.Else)
 		}
 	}
 	return nil
@@ -195,7 +316,8 @@ func (v *AstInterpreter) VisitIfStatement(node *ast.IfStatement) interface{} {
 func (v *AstInterpreter) VisitLogicalExpr(node *ast.LogicalExpr) interface{} {
 	if node.Operator.Type == lexer.AND {
 		leftValue := node.Left.Accept(v)
-		if !cast.ToBool(leftValue) {
+		v.branch(node, IsTruthy(leftValue))
+		if !IsTruthy(leftValue) {
 			return leftValue
 		} else {
 			rightValue := node.Right.Accept(v)
@@ -204,31 +326,161 @@ func (v *AstInterpreter) VisitLogicalExpr(node *ast.LogicalExpr) interface{} {
 	}
 	if node.Operator.Type == lexer.OR {
 		leftValue := node.Left.Accept(v)
-		if !cast.ToBool(leftValue) {
-			rightValue := cast.ToBool(node.Right.Accept(v))
+		v.branch(node, IsTruthy(leftValue))
+		if !IsTruthy(leftValue) {
+			rightValue := node.Right.Accept(v)
 			return rightValue
 		} else {
 			return leftValue
 		}
 
 	}
+	if node.Operator.Type == lexer.QUESTION_QUESTION {
+		leftValue := node.Left.Accept(v)
+		v.branch(node, leftValue != nil)
+		if leftValue == nil {
+			return node.Right.Accept(v)
+		}
+		return leftValue
+	}
 	return nil
 }
 
+func (v *AstInterpreter) VisitConditionalExpr(node *ast.ConditionalExpr) interface{} {
+	condition := IsTruthy(node.Condition.Accept(v))
+	v.branch(node, condition)
+	if condition {
+		return node.Then.Accept(v)
+	}
+	return node.Else.Accept(v)
+}
+
 func (v *AstInterpreter) VisitGetExpr(node *ast.GetExpr) interface{} {
-	expr := node.Expr.Accept(v)
-	if instance, ok := expr.(*LoxInstance); ok {
-		return instance.Get(node.Name)
-	} else {
+	return endChain(node.ChainEnd, v.Property(node, node.Expr.Accept(v)))
+}
+
+// shortCircuit is the value of the links of an optional chain once a ?.
+// found nil, the last link of the chain turns it into nil.
+type shortCircuit struct{}
+
+// endChain returns value, or nil for a short circuited chain when node is
+// the last link of the chain.
+func endChain(chainEnd bool, value interface{}) interface{} {
+	if _, ok := value.(shortCircuit); ok && chainEnd {
+		return nil
+	}
+	return value
+}
+
+// Property gets the property of an object, for an optional GetExpr a nil
+// object short circuits the rest of the chain.
+func (v *AstInterpreter) Property(node *ast.GetExpr, expr interface{}) interface{} {
+	if _, ok := expr.(shortCircuit); ok || (expr == nil && node.Optional) {
+		return shortCircuit{}
+	}
+	switch object := expr.(type) {
+	case *LoxInstance:
+		return v.GetProperty(object, node.Name)
+	case *LoxClass:
+		return object.Get(node.Name)
+	case string:
+		return bindMethod(object, stringMethods, node.Name)
+	case *LoxModule:
+		return object.Get(node.Name)
+	case *LoxMap:
+		return bindMethod(object, mapMethods, node.Name)
+	case *LoxRegex:
+		return bindMethod(object, regexMethods, node.Name)
+	default:
 		panic(glox_error.NewRuntimeError(utils.ONLY_INSTANCES_HAVE_PROPERTIES, node.Name))
 	}
 }
 
+// VisitIndexExpr indexes a list, or a string by code point which gives a
+// string of one character, or looks up a key of a map which gives nil for
+// missing keys.
+func (v *AstInterpreter) VisitIndexExpr(node *ast.IndexExpr) interface{} {
+	object := node.Expr.Accept(v)
+	if _, ok := object.(shortCircuit); ok {
+		return endChain(node.ChainEnd, object)
+	}
+	indexValue := node.Index.Accept(v)
+	if m, ok := object.(*LoxMap); ok {
+		key, ok := indexValue.(string)
+		if !ok {
+			panic(glox_error.NewRuntimeError(utils.INVALID_MAP_KEY, node.Bracket))
+		}
+		value, _ := m.Get(key)
+		return value
+	}
+	index, ok := ToInteger(indexValue)
+	if !ok {
+		panic(glox_error.NewRuntimeError(utils.INVALID_INDEX, node.Bracket))
+	}
+	switch object := object.(type) {
+	case string:
+		runes := []rune(object)
+		if index < 0 || index >= int64(len(runes)) {
+			panic(glox_error.NewRuntimeError(utils.INDEX_OUT_OF_RANGE, node.Bracket))
+		}
+		return string(runes[index])
+	case *LoxList:
+		if index < 0 || index >= int64(len(object.Elements)) {
+			panic(glox_error.NewRuntimeError(utils.INDEX_OUT_OF_RANGE, node.Bracket))
+		}
+		return object.Elements[index]
+	default:
+		panic(glox_error.NewRuntimeError(utils.ONLY_INDEX_STRINGS_AND_LISTS, node.Bracket))
+	}
+}
+
+// GetProperty reads a property of an instance through its getter if the
+// class has one.
+func (v *AstInterpreter) GetProperty(instance *LoxInstance, name lexer.Token) interface{} {
+	if getter := instance.Class.FindGetter(name.Lexeme); getter != nil {
+		v.callSite = name
+		return getter.Bind(instance).Call(v, nil)
+	}
+	return instance.Get(name)
+}
+
+// SetProperty assigns a property of an instance through its setter if the
+// class has one, properties with only a getter can't be assigned. Classes
+// declaring fields only accept those, and const fields are assigned once.
+func (v *AstInterpreter) SetProperty(instance *LoxInstance, name lexer.Token, value interface{}) {
+	if setter := instance.Class.FindSetter(name.Lexeme); setter != nil {
+		v.callSite = name
+		setter.Bind(instance).Call(v, []interface{}{value})
+		return
+	}
+	if instance.Class.FindGetter(name.Lexeme) != nil {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.NO_SETTER, name.Lexeme), name))
+	}
+	if field := instance.Class.FindField(name.Lexeme); field != nil {
+		if field.Const {
+			if instance.frozen[name.Lexeme] {
+				panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.ASSIGN_TO_CONST_FIELD, name.Lexeme), name))
+			}
+			instance.freeze(name.Lexeme)
+		}
+	} else if instance.Class.DeclaresFields() {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.UNDECLARED_FIELD, name.Lexeme, instance.Class.Name), name))
+	}
+	instance.Set(name, value)
+}
+
 func (v *AstInterpreter) VisitSetExpr(node *ast.SetExpr) interface{} {
 	expr := node.Expr.Accept(v)
 	if instance, ok := expr.(*LoxInstance); ok {
+		var current interface{}
+		if node.Operator != nil {
+			current = v.GetProperty(instance, node.Name)
+		}
 		value := node.Value.Accept(v)
-		instance.Set(node.Name, value)
+		if node.Operator != nil {
+			value = v.Binary(*node.Operator, current, value)
+		}
+		v.SetProperty(instance, node.Name, value)
 		return value
 	} else {
 		panic(glox_error.NewRuntimeError(utils.ONLY_INSTANCES_HAVE_PROPERTIES, node.Name))
@@ -242,31 +494,72 @@ func (v *AstInterpreter) VisitClassDeclaration(node *ast.ClassDeclaration) inter
 
 	if node.SuperClass != nil {
 		var ok = false
-		superClass := node.SuperClass.Accept(v)
-		superClass, ok = superClass.(*LoxClass)
+		superClass, ok = node.SuperClass.Accept(v).(*LoxClass)
 		if !ok {
-			panic(glox_error.NewRuntimeError(utils.SUPER_CLASS_MUST_BE_CLASS, node.Name))
+			panic(glox_error.NewRuntimeError(utils.SUPER_CLASS_MUST_BE_CLASS, node.SuperClass.Name))
 		}
+	}
 
+	var traits []*LoxTrait
+	for _, traitNode := range node.Traits {
+		trait, ok := traitNode.Accept(v).(*LoxTrait)
+		if !ok {
+			panic(glox_error.NewRuntimeError(utils.MUST_BE_TRAIT, traitNode.Name))
+		}
+		traits = append(traits, trait)
+	}
+
+	// super looks methods up in the traits, then in the superclass
+	hasSuper := node.SuperClass != nil || len(traits) != 0
+	if hasSuper {
 		v.EnterScope()
-		v.Env.Define("super", superClass)
+		v.Env.Define("super", &LoxClass{
+			Name:       node.Name.Lexeme,
+			Traits:     traits,
+			SuperClass: superClass,
+		})
 	}
 
 	class := NewLoxClass(node.Name.Lexeme, superClass)
+	class.Traits = traits
+	class.Env = v.Env
+	for _, field := range node.Fields {
+		class.Fields = append(class.Fields, &LoxField{
+			Name:        field.Name.Lexeme,
+			Initializer: field.Initializer,
+			Const:       field.Const,
+		})
+	}
 	for _, method := range node.Methods {
-		var isInitializer = false
-		if method.Name.Lexeme == "init" {
-			isInitializer = true
+		switch method.Kind {
+		case ast.ClassMethodKind:
+			class.MetaClass.Methods[method.Name.Lexeme] = NewLoxFunction(method, v.Env, false)
+		case ast.GetterKind:
+			class.Getters[method.Name.Lexeme] = NewLoxFunction(method, v.Env, false)
+		case ast.SetterKind:
+			class.Setters[method.Name.Lexeme] = NewLoxFunction(method, v.Env, false)
+		default:
+			isInitializer := method.Name.Lexeme == "init"
+			class.Methods[method.Name.Lexeme] = NewLoxFunction(method, v.Env, isInitializer)
 		}
-		class.Methods[method.Name.Lexeme] = NewLoxFunction(method, v.Env, isInitializer)
 	}
-	if node.SuperClass != nil {
+	class.CheckTraits(node.Name)
+	if hasSuper {
 		v.ExitScope()
 	}
 	v.Env.Assign(node.Name, class, 0)
 	return nil
 }
 
+func (v *AstInterpreter) VisitTraitDeclaration(node *ast.TraitDeclaration) interface{} {
+	trait := NewLoxTrait(node.Name.Lexeme)
+	for _, method := range node.Methods {
+		trait.Methods[method.Name.Lexeme] = NewLoxFunction(method, v.Env, false)
+	}
+	v.Env.Define(node.Name.Lexeme, trait)
+	return nil
+}
+
 func (v *AstInterpreter) VisitSuperExpr(node *ast.SuperExpr) interface{} {
 	distance := v.VariableBindDistance[node]
 	superClass := v.Env.Get(node.Keyword, distance)
@@ -286,34 +579,159 @@ func (v *AstInterpreter) VisitSuperExpr(node *ast.SuperExpr) interface{} {
 }
 
 func (v *AstInterpreter) VisitWhileStatement(node *ast.WhileStatement) interface{} {
-	value := cast.ToBool(node.Expr.Accept(v))
+	value := IsTruthy(node.Expr.Accept(v))
+	v.branch(node, value)
 	for value {
-		node.Then.Accept(v)
-		value = cast.ToBool(node.Expr.Accept(v))
+		v.Execute(node.Then)
+		value = IsTruthy(node.Expr.Accept(v))
+		v.branch(node, value)
 	}
 	return nil
 }
 
+// VisitTryStatement runs the catch block when a runtime error stops the try
+// block, exit and return aren't caught.
+func (v *AstInterpreter) VisitTryStatement(node *ast.TryStatement) interface{} {
+	if message, caught := v.try(node.Body); caught {
+		v.EnterScope()
+		v.Env.Define(node.Name.Lexeme, message)
+		node.Catch.Accept(v)
+		v.ExitScope()
+	}
+	return nil
+}
+
+// try runs block and recovers the runtime error stopping it, leaving the
+// scope the block was entered from.
+func (v *AstInterpreter) try(block ast.Node) (message string, caught bool) {
+	env := v.Env
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeError, ok := r.(*glox_error.RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			v.Env = env
+			message, caught = runtimeError.Message(), true
+		}
+	}()
+	block.Accept(v)
+	return "", false
+}
+
 func (v *AstInterpreter) VisitThisExpr(node *ast.ThisExpr) interface{} {
 	distance := v.VariableBindDistance[node]
 	return v.Env.Get(node.Keyword, distance)
 
 }
 
+// Execute runs a statement, notifying the hooks first.
+func (v *AstInterpreter) Execute(node ast.Node) {
+	for _, hook := range v.Hooks {
+		hook.BeforeStatement(node, v.Env)
+	}
+	node.Accept(v)
+}
+
+// branch notifies the hooks of the outcome of a condition.
+func (v *AstInterpreter) branch(node ast.Node, taken bool) {
+	for _, hook := range v.Hooks {
+		if branchHook, ok := hook.(BranchHook); ok {
+			branchHook.Branch(node, taken)
+		}
+	}
+}
+
+// CallSite is the token of the call being made, native functions raise
+// their errors at it.
+func (v *AstInterpreter) CallSite() lexer.Token {
+	return v.callSite
+}
+
+// Interpret runs node, the statements of a program are run one by one, and
+// returns the runtime error that stopped it.
+func (v *AstInterpreter) Interpret(node ast.Node) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if runtimeError, ok := r.(*glox_error.RuntimeError); ok {
+				err = runtimeError
+				return
+			}
+			if exit, ok := r.(*Exit); ok {
+				err = exit
+				return
+			}
+			panic(r)
+		}
+	}()
+	if program, ok := node.(*ast.Program); ok {
+		for _, statement := range program.Statements {
+			v.Execute(statement)
+		}
+		return nil
+	}
+	node.Accept(v)
+	return nil
+}
+
+func (v *AstInterpreter) AddHook(hook StatementHook) {
+	v.Hooks = append(v.Hooks, hook)
+}
+
+// Evaluate evaluates expr in env with hooks disabled. Variables are resolved
+// against the scopes of env, so tools can evaluate expressions in a frame
+// that is already running.
+func (v *AstInterpreter) Evaluate(expr ast.Node, env *environment.Env) (value interface{}, err error) {
+	resolver := NewEnvResolver(env)
+	expr.Accept(resolver)
+	if len(resolver.Errors) != 0 {
+		return nil, resolver.Errors[0]
+	}
+	for node, distance := range resolver.VariableBindingDistances {
+		v.VariableBindDistance[node] = distance
+	}
+
+	envBackup, hooksBackup, framesBackup := v.Env, v.Hooks, v.Frames
+	v.Env, v.Hooks = env, nil
+	defer func() {
+		v.Env, v.Hooks, v.Frames = envBackup, hooksBackup, framesBackup
+		if r := recover(); r != nil {
+			if runtimeError, ok := r.(*glox_error.RuntimeError); ok {
+				err = runtimeError
+				return
+			}
+			if exit, ok := r.(*Exit); ok {
+				err = exit
+				return
+			}
+			panic(r)
+		}
+	}()
+	return expr.Accept(v), nil
+}
+
 func (v *AstInterpreter) CheckNumberOperand(token lexer.Token, value interface{}) {
-	if _, ok := value.(float64); !ok {
+	if !IsNumber(value) {
 		panic(glox_error.NewRuntimeError(utils.INVALID_OPERAND_NUMBER, token))
 	}
 }
 
 func (v *AstInterpreter) CheckNumberOperands(token lexer.Token, lefValue interface{}, rightValue interface{}) {
-	_, leftOk := lefValue.(float64)
-	_, rightOk := rightValue.(float64)
-	if !leftOk || !rightOk {
+	if !IsNumber(lefValue) || !IsNumber(rightValue) {
 		panic(glox_error.NewRuntimeError(utils.INVALID_OPERAND_NUMBERS, token))
 	}
 }
 
+// CheckIntegerOperands converts numbers with integer values to integers.
+func (v *AstInterpreter) CheckIntegerOperands(token lexer.Token, leftValue interface{}, rightValue interface{}) (int64, int64) {
+	left, leftOk := ToInteger(leftValue)
+	right, rightOk := ToInteger(rightValue)
+	if !leftOk || !rightOk {
+		panic(glox_error.NewRuntimeError(utils.INVALID_OPERAND_INTEGERS, token))
+	}
+	return left, right
+}
+
 func (v *AstInterpreter) EnterScope() {
 	newEnv := environment.NewEnvironment(v.Env)
 	v.Env = newEnv
@@ -0,0 +1,383 @@
+package visitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/utils"
+)
+
+// JSON is the json module. parse turns objects into maps and arrays into
+// lists, stringify also accepts instances, which are written as objects of
+// their fields.
+var JSON = &LoxModule{
+	Name: "json",
+	Members: map[string]interface{}{
+		"parse": &NativeFunction{
+			Name:   "json.parse",
+			Params: 1,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				parser := &jsonParser{source: v.StringArgument("json.parse", arguments, 0)}
+				value, err := parser.Parse()
+				if err != nil {
+					panic(glox_error.NewRuntimeError(err.Error(), v.CallSite()))
+				}
+				return value
+			},
+		},
+		// stringify writes compact JSON when indent is nil, otherwise every
+		// element on its own line indented by indent, a string or a number of
+		// spaces
+		"stringify": &NativeFunction{
+			Name:   "json.stringify",
+			Params: 2,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				var indent string
+				switch value := arguments[1].(type) {
+				case nil:
+				case string:
+					indent = value
+				default:
+					n, ok := ToInteger(value)
+					if !ok || n < 0 {
+						panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_ARGUMENT, 2, "json.stringify", "nil, a string or a non-negative integer"), v.CallSite()))
+					}
+					indent = strings.Repeat(" ", int(n))
+				}
+				encoder := &jsonEncoder{indent: indent, visiting: make(map[interface{}]bool)}
+				if err := encoder.encode(arguments[0], 0); err != nil {
+					panic(glox_error.NewRuntimeError(err.Error(), v.CallSite()))
+				}
+				return encoder.sb.String()
+			},
+		},
+	},
+}
+
+// jsonParser parses a JSON document, its errors carry the line and column
+// where parsing failed.
+type jsonParser struct {
+	source  string
+	current int
+}
+
+func (p *jsonParser) Parse() (interface{}, error) {
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+	if p.current < len(p.source) {
+		return nil, p.unexpected()
+	}
+	return value, nil
+}
+
+func (p *jsonParser) errorf(format string, args ...interface{}) error {
+	line, column := 1, 1
+	for _, c := range p.source[:p.current] {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return fmt.Errorf(utils.INVALID_JSON, line, column, fmt.Sprintf(format, args...))
+}
+
+func (p *jsonParser) unexpected() error {
+	if p.current >= len(p.source) {
+		return p.errorf("unexpected end of input")
+	}
+	c, _ := utf8.DecodeRuneInString(p.source[p.current:])
+	return p.errorf("unexpected character %q", c)
+}
+
+func (p *jsonParser) skipWhitespace() {
+	for p.current < len(p.source) {
+		switch p.source[p.current] {
+		case ' ', '\t', '\n', '\r':
+			p.current++
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsonParser) peek() byte {
+	if p.current >= len(p.source) {
+		return 0
+	}
+	return p.source[p.current]
+}
+
+func (p *jsonParser) value() (interface{}, error) {
+	p.skipWhitespace()
+	switch c := p.peek(); {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"':
+		return p.string()
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.number()
+	case strings.HasPrefix(p.source[p.current:], "true"):
+		p.current += len("true")
+		return true, nil
+	case strings.HasPrefix(p.source[p.current:], "false"):
+		p.current += len("false")
+		return false, nil
+	case strings.HasPrefix(p.source[p.current:], "null"):
+		p.current += len("null")
+		return nil, nil
+	default:
+		return nil, p.unexpected()
+	}
+}
+
+func (p *jsonParser) object() (interface{}, error) {
+	p.current++
+	object := NewLoxMap()
+	p.skipWhitespace()
+	if p.peek() == '}' {
+		p.current++
+		return object, nil
+	}
+	for {
+		p.skipWhitespace()
+		if p.peek() != '"' {
+			return nil, p.unexpected()
+		}
+		key, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		p.skipWhitespace()
+		if p.peek() != ':' {
+			return nil, p.unexpected()
+		}
+		p.current++
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		object.Set(key.(string), value)
+		p.skipWhitespace()
+		switch p.peek() {
+		case ',':
+			p.current++
+		case '}':
+			p.current++
+			return object, nil
+		default:
+			return nil, p.unexpected()
+		}
+	}
+}
+
+func (p *jsonParser) array() (interface{}, error) {
+	p.current++
+	var elements []interface{}
+	p.skipWhitespace()
+	if p.peek() == ']' {
+		p.current++
+		return NewLoxList(elements), nil
+	}
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+		p.skipWhitespace()
+		switch p.peek() {
+		case ',':
+			p.current++
+		case ']':
+			p.current++
+			return NewLoxList(elements), nil
+		default:
+			return nil, p.unexpected()
+		}
+	}
+}
+
+// string scans a string literal and leaves decoding its escapes to
+// encoding/json.
+func (p *jsonParser) string() (interface{}, error) {
+	start := p.current
+	p.current++
+	for {
+		switch c := p.peek(); {
+		case p.current >= len(p.source):
+			return nil, p.errorf("unterminated string")
+		case c == '"':
+			p.current++
+			var s string
+			if err := json.Unmarshal([]byte(p.source[start:p.current]), &s); err != nil {
+				p.current = start
+				return nil, p.errorf("invalid string")
+			}
+			return s, nil
+		case c == '\\':
+			p.current += 2
+		case c < ' ':
+			return nil, p.unexpected()
+		default:
+			p.current++
+		}
+	}
+}
+
+// number scans a number, integers that fit are exact like Lox integer
+// literals.
+func (p *jsonParser) number() (interface{}, error) {
+	start := p.current
+	if p.peek() == '-' {
+		p.current++
+	}
+	// Leading zeros aren't allowed, 0 can only be followed by the fraction
+	if p.peek() == '0' {
+		p.current++
+	} else if !p.digits() {
+		return nil, p.unexpected()
+	}
+	integer := true
+	if p.peek() == '.' {
+		integer = false
+		p.current++
+		if !p.digits() {
+			return nil, p.unexpected()
+		}
+	}
+	if c := p.peek(); c == 'e' || c == 'E' {
+		integer = false
+		p.current++
+		if c := p.peek(); c == '+' || c == '-' {
+			p.current++
+		}
+		if !p.digits() {
+			return nil, p.unexpected()
+		}
+	}
+	text := p.source[start:p.current]
+	if integer {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i, nil
+		}
+	}
+	f, _ := strconv.ParseFloat(text, 64)
+	return f, nil
+}
+
+func (p *jsonParser) digits() bool {
+	start := p.current
+	for p.peek() >= '0' && p.peek() <= '9' {
+		p.current++
+	}
+	return p.current > start
+}
+
+// jsonEncoder writes Lox values as JSON, visiting holds the lists, maps and
+// instances being written to detect cycles.
+type jsonEncoder struct {
+	sb       strings.Builder
+	indent   string
+	visiting map[interface{}]bool
+}
+
+func (e *jsonEncoder) newline(depth int) {
+	if e.indent == "" {
+		return
+	}
+	e.sb.WriteString("\n")
+	e.sb.WriteString(strings.Repeat(e.indent, depth))
+}
+
+func (e *jsonEncoder) encode(value interface{}, depth int) error {
+	switch value := value.(type) {
+	case nil:
+		e.sb.WriteString("null")
+	case bool:
+		e.sb.WriteString(strconv.FormatBool(value))
+	case int64:
+		e.sb.WriteString(strconv.FormatInt(value, 10))
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf(utils.INVALID_JSON_VALUE, Stringify(value))
+		}
+		e.sb.WriteString(formatFloat(value))
+	case string:
+		e.string(value)
+	case *LoxList:
+		return e.container(value, '[', ']', len(value.Elements), depth, func(i int) error {
+			return e.encode(value.Elements[i], depth+1)
+		})
+	case *LoxMap:
+		return e.container(value, '{', '}', len(value.Keys), depth, func(i int) error {
+			return e.member(value.Keys[i], value.Values[value.Keys[i]], depth)
+		})
+	case *LoxInstance:
+		names := value.PublicFields()
+		sort.Strings(names)
+		return e.container(value, '{', '}', len(names), depth, func(i int) error {
+			return e.member(names[i], value.Fields[names[i]], depth)
+		})
+	default:
+		return fmt.Errorf(utils.INVALID_JSON_VALUE, Stringify(value))
+	}
+	return nil
+}
+
+// container writes the n elements of an array or object between open and
+// close.
+func (e *jsonEncoder) container(value interface{}, open byte, close byte, n int, depth int, element func(i int) error) error {
+	if e.visiting[value] {
+		return errors.New(utils.CYCLIC_JSON_VALUE)
+	}
+	e.visiting[value] = true
+	defer delete(e.visiting, value)
+
+	e.sb.WriteByte(open)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			e.sb.WriteByte(',')
+		}
+		e.newline(depth + 1)
+		if err := element(i); err != nil {
+			return err
+		}
+	}
+	if n > 0 {
+		e.newline(depth)
+	}
+	e.sb.WriteByte(close)
+	return nil
+}
+
+func (e *jsonEncoder) member(key string, value interface{}, depth int) error {
+	e.string(key)
+	e.sb.WriteString(":")
+	if e.indent != "" {
+		e.sb.WriteString(" ")
+	}
+	return e.encode(value, depth+1)
+}
+
+func (e *jsonEncoder) string(s string) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	e.sb.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
@@ -3,48 +3,188 @@ package visitor
 import (
 	"fmt"
 
+	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/environment"
 	"github.com/jameslahm/glox/glox_error"
 	"github.com/jameslahm/glox/lexer"
 	"github.com/jameslahm/glox/utils"
 )
 
 type LoxClass struct {
-	Name       string
-	Methods    map[string]*LoxFunction
+	Name    string
+	Methods map[string]*LoxFunction
+	// Getters and Setters run when a property of an instance is read or
+	// assigned, they take precedence over fields
+	Getters    map[string]*LoxFunction
+	Setters    map[string]*LoxFunction
 	SuperClass *LoxClass
+	// Traits are the traits mixed into the class, their methods come after
+	// the methods of the class and before the ones of the superclass
+	Traits []*LoxTrait
+	// Fields are the fields declared in the class body, their initializers
+	// run in Env for every new instance. Instances of a class declaring
+	// fields, or inheriting them, only accept those fields.
+	Fields []*LoxField
+	Env    *environment.Env
+	// MetaClass holds the class methods as its methods, it inherits from
+	// the metaclass of the superclass
+	MetaClass *LoxClass
+}
+
+// LoxField is a field declared in a class body, a const field can only be
+// assigned once.
+type LoxField struct {
+	Name        string
+	Initializer ast.Node
+	Const       bool
 }
 
 func NewLoxClass(name string, superClass *LoxClass) *LoxClass {
+	metaClass := &LoxClass{
+		Name:    name + " metaclass",
+		Methods: make(map[string]*LoxFunction),
+	}
+	if superClass != nil {
+		metaClass.SuperClass = superClass.MetaClass
+	}
 	return &LoxClass{
 		Name:       name,
 		Methods:    make(map[string]*LoxFunction),
+		Getters:    make(map[string]*LoxFunction),
+		Setters:    make(map[string]*LoxFunction),
 		SuperClass: superClass,
+		MetaClass:  metaClass,
 	}
 }
 
-func (c *LoxClass) Call(v *AstInterpreter, arguments []interface{}) interface{} {
-	instance := &LoxInstance{
-		Class: c,
+// Get returns the class method name, class methods don't have this.
+func (c *LoxClass) Get(name lexer.Token) interface{} {
+	if method := c.MetaClass.FindMethod(name.Lexeme); method != nil {
+		return method
 	}
-	if initializer, ok := c.Methods["init"]; ok {
+	panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.UNDEFINED_PROPERTY, name.Lexeme), name))
+}
+
+func (c *LoxClass) Call(v *AstInterpreter, arguments []interface{}) interface{} {
+	instance := NewLoxInstance(c)
+	c.initializeFields(v, instance)
+	if initializer := c.FindMethod("init"); initializer != nil {
 		initializer.Bind(instance).Call(v, arguments)
 	}
 	return instance
 }
 
+// initializeFields sets the declared fields of instance, the ones of the
+// superclasses first.
+func (c *LoxClass) initializeFields(v *AstInterpreter, instance *LoxInstance) {
+	if c.SuperClass != nil {
+		c.SuperClass.initializeFields(v, instance)
+	}
+	if len(c.Fields) == 0 {
+		return
+	}
+	v.NewExecuteScope(c.Env)
+	defer v.RestoreExecuteScope()
+	v.Env.Define("this", instance)
+	for _, field := range c.Fields {
+		var value interface{}
+		if field.Initializer != nil {
+			value = field.Initializer.Accept(v)
+			if field.Const {
+				instance.freeze(field.Name)
+			}
+		}
+		instance.Fields[field.Name] = value
+	}
+}
+
 func (c *LoxClass) Arity() int {
-	if initialzer, ok := c.Methods["init"]; ok {
+	if initialzer := c.FindMethod("init"); initialzer != nil {
 		return initialzer.Arity()
 	}
 	return 0
 }
 
-func (c *LoxClass) GetMethod(token lexer.Token) *LoxFunction {
-	if v, ok := c.Methods[token.Lexeme]; ok {
+// FindMethod looks name up in the class, its traits and its superclasses,
+// it returns nil when there is no such method.
+func (c *LoxClass) FindMethod(name string) *LoxFunction {
+	if v, ok := c.Methods[name]; ok {
 		return v
 	}
+	for _, trait := range c.Traits {
+		if v, ok := trait.Methods[name]; ok {
+			return v
+		}
+	}
 	if c.SuperClass != nil {
-		return c.SuperClass.GetMethod(token)
+		return c.SuperClass.FindMethod(name)
+	}
+	return nil
+}
+
+func (c *LoxClass) FindGetter(name string) *LoxFunction {
+	for class := c; class != nil; class = class.SuperClass {
+		if getter, ok := class.Getters[name]; ok {
+			return getter
+		}
+	}
+	return nil
+}
+
+func (c *LoxClass) FindSetter(name string) *LoxFunction {
+	for class := c; class != nil; class = class.SuperClass {
+		if setter, ok := class.Setters[name]; ok {
+			return setter
+		}
+	}
+	return nil
+}
+
+// CheckTraits reports a method defined by two traits of the class, unless
+// the class defines it itself, as a runtime error at token.
+func (c *LoxClass) CheckTraits(token lexer.Token) {
+	owners := make(map[string]*LoxTrait)
+	for _, trait := range c.Traits {
+		for _, name := range trait.MethodNames() {
+			if _, ok := c.Methods[name]; ok {
+				continue
+			}
+			if owner, ok := owners[name]; ok {
+				panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.TRAIT_CONFLICT, name, owner.Name, trait.Name), token))
+			}
+			owners[name] = trait
+		}
+	}
+}
+
+// GetMethod returns the method name of the class, which is resolved in the
+// order of FindMethod: the class, its traits, then its superclass.
+func (c *LoxClass) GetMethod(token lexer.Token) *LoxFunction {
+	if method := c.FindMethod(token.Lexeme); method != nil {
+		return method
 	}
 	panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.UNDEFINED_PROPERTY, token.Lexeme), token))
 }
+
+// FindField looks the declared field name up in the class and its
+// superclasses, it returns nil when there is no such field.
+func (c *LoxClass) FindField(name string) *LoxField {
+	for class := c; class != nil; class = class.SuperClass {
+		for _, field := range class.Fields {
+			if field.Name == name {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// DeclaresFields reports whether the class or a superclass declares fields.
+func (c *LoxClass) DeclaresFields() bool {
+	for class := c; class != nil; class = class.SuperClass {
+		if len(class.Fields) != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -18,28 +18,41 @@ type LoxCallable interface {
 }
 
 func (f *LoxFunction) Call(v *AstInterpreter, arguments []interface{}) (ret interface{}) {
-	ok := false
-
+	frame := &CallFrame{
+		Function:  f,
+		CallSite:  v.callSite,
+		CallerEnv: v.Env,
+	}
+	v.Frames = append(v.Frames, frame)
+	for _, hook := range v.Hooks {
+		if callHook, ok := hook.(CallHook); ok {
+			callHook.EnterFunction(frame)
+		}
+	}
 	v.NewExecuteScope(f.Env)
 	for i, param := range f.Node.Params {
 		v.Env.Define(param.Lexeme, arguments[i])
 	}
 	defer func() {
-		r := recover()
-		if !ok {
-			v.RestoreExecuteScope()
-			ret = r
-			if f.IsInitializer {
-				ret = f.GetThis()
+		v.RestoreExecuteScope()
+		v.Frames = v.Frames[:len(v.Frames)-1]
+		for _, hook := range v.Hooks {
+			if callHook, ok := hook.(CallHook); ok {
+				callHook.ExitFunction(frame)
 			}
 		}
+		if r := recover(); r != nil {
+			returnValue, ok := r.(*ReturnValue)
+			if !ok {
+				panic(r)
+			}
+			ret = returnValue.Value
+		}
+		if f.IsInitializer {
+			ret = f.GetThis()
+		}
 	}()
-	f.Node.Body.Accept(v)
-	v.RestoreExecuteScope()
-	ok = true
-	if f.IsInitializer {
-		return f.GetThis()
-	}
+	v.Execute(f.Node.Body)
 	return nil
 }
 
@@ -62,8 +75,9 @@ func (f *LoxFunction) Bind(instance *LoxInstance) *LoxFunction {
 	newEnv := environment.NewEnvironment(env)
 	newEnv.Define("this", instance)
 	return &LoxFunction{
-		Node: f.Node,
-		Env:  newEnv,
+		Node:          f.Node,
+		Env:           newEnv,
+		IsInitializer: f.IsInitializer,
 	}
 }
 
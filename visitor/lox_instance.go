@@ -1,12 +1,16 @@
 package visitor
 
 import (
+	"strings"
+
 	"github.com/jameslahm/glox/lexer"
 )
 
 type LoxInstance struct {
 	Class  *LoxClass
 	Fields map[string]interface{}
+	// frozen holds the const fields that have been assigned
+	frozen map[string]bool
 }
 
 func NewLoxInstance(class *LoxClass) *LoxInstance {
@@ -27,3 +31,45 @@ func (instance *LoxInstance) Get(token lexer.Token) interface{} {
 func (instance *LoxInstance) Set(token lexer.Token, value interface{}) {
 	instance.Fields[token.Lexeme] = value
 }
+
+func (instance *LoxInstance) freeze(name string) {
+	if instance.frozen == nil {
+		instance.frozen = make(map[string]bool)
+	}
+	instance.frozen[name] = true
+}
+
+// IsPrivate reports whether name is a private #name.
+func IsPrivate(name string) bool {
+	return strings.HasPrefix(name, "#")
+}
+
+// PublicFields returns the names of the fields of instance that aren't
+// private, in no particular order.
+func (instance *LoxInstance) PublicFields() []string {
+	names := make([]string, 0, len(instance.Fields))
+	for name := range instance.Fields {
+		if !IsPrivate(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// PrivateNames returns the #names of the fields and methods of instance.
+func (instance *LoxInstance) PrivateNames() map[string]bool {
+	names := make(map[string]bool)
+	for name := range instance.Fields {
+		if IsPrivate(name) {
+			names[name] = true
+		}
+	}
+	for class := instance.Class; class != nil; class = class.SuperClass {
+		for name := range class.Methods {
+			if IsPrivate(name) {
+				names[name] = true
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,28 @@
+package visitor
+
+import (
+	"strings"
+)
+
+// LoxList is a list of Lox values, lists are shared by reference like
+// instances.
+type LoxList struct {
+	Elements []interface{}
+}
+
+func NewLoxList(elements []interface{}) *LoxList {
+	return &LoxList{Elements: elements}
+}
+
+func (list *LoxList) String() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i, element := range list.Elements {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(Inspect(element))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
@@ -0,0 +1,69 @@
+package visitor
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LoxMap maps strings to Lox values and keeps its keys in the order they
+// were added, json.parse turns JSON objects into maps.
+type LoxMap struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{Values: make(map[string]interface{})}
+}
+
+func (m *LoxMap) Get(key string) (interface{}, bool) {
+	value, ok := m.Values[key]
+	return value, ok
+}
+
+// Set adds or replaces the value of key, a replaced key keeps its position.
+func (m *LoxMap) Set(key string, value interface{}) {
+	if _, ok := m.Values[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Values[key] = value
+}
+
+func (m *LoxMap) String() string {
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, key := range m.Keys {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(strconv.Quote(key))
+		sb.WriteString(": ")
+		sb.WriteString(Inspect(m.Values[key]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// mapMethod adapts a method of LoxMap values to a boundMethod.
+func mapMethod(params int, function func(v *AstInterpreter, m *LoxMap, arguments []interface{}) interface{}) *boundMethod {
+	return &boundMethod{params, func(v *AstInterpreter, receiver interface{}, arguments []interface{}) interface{} {
+		return function(v, receiver.(*LoxMap), arguments)
+	}}
+}
+
+var mapMethods = map[string]*boundMethod{
+	"len": mapMethod(0, func(v *AstInterpreter, m *LoxMap, arguments []interface{}) interface{} {
+		return int64(len(m.Keys))
+	}),
+	"keys": mapMethod(0, func(v *AstInterpreter, m *LoxMap, arguments []interface{}) interface{} {
+		keys := make([]interface{}, len(m.Keys))
+		for i, key := range m.Keys {
+			keys[i] = key
+		}
+		return NewLoxList(keys)
+	}),
+	"has": mapMethod(1, func(v *AstInterpreter, m *LoxMap, arguments []interface{}) interface{} {
+		_, ok := m.Get(v.StringArgument("has", arguments, 0))
+		return ok
+	}),
+}
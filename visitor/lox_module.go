@@ -0,0 +1,23 @@
+package visitor
+
+import (
+	"fmt"
+
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/utils"
+)
+
+// LoxModule is a namespace of native values like math, its members can be
+// read but not assigned.
+type LoxModule struct {
+	Name    string
+	Members map[string]interface{}
+}
+
+func (module *LoxModule) Get(name lexer.Token) interface{} {
+	if member, ok := module.Members[name.Lexeme]; ok {
+		return member
+	}
+	panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.UNDEFINED_PROPERTY, name.Lexeme), name))
+}
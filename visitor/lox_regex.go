@@ -0,0 +1,97 @@
+package visitor
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/utils"
+)
+
+// LoxRegex is a compiled regular expression in the syntax of Go's regexp
+// package, created by calling Regex.
+type LoxRegex struct {
+	Regexp *regexp.Regexp
+}
+
+func (r *LoxRegex) String() string {
+	return fmt.Sprintf("<regex %s>", r.Regexp.String())
+}
+
+// Regex compiles a pattern, the regexes of an interpreter are cached by
+// pattern so compiling the same pattern in a loop is cheap.
+var Regex = &NativeFunction{
+	Name:   "Regex",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		pattern := v.StringArgument("Regex", arguments, 0)
+		if regex, ok := v.regexes[pattern]; ok {
+			return regex
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_REGEX, err), v.CallSite()))
+		}
+		regex := &LoxRegex{Regexp: compiled}
+		if v.regexes == nil {
+			v.regexes = make(map[string]*LoxRegex)
+		}
+		v.regexes[pattern] = regex
+		return regex
+	},
+}
+
+// groups turns the submatches of a match into a list of the whole match and
+// its capture groups, groups that didn't participate are nil.
+func groups(s string, indexes []int) *LoxList {
+	elements := make([]interface{}, len(indexes)/2)
+	for i := range elements {
+		if start := indexes[2*i]; start >= 0 {
+			elements[i] = s[start:indexes[2*i+1]]
+		}
+	}
+	return NewLoxList(elements)
+}
+
+// regexMethod adapts a method of LoxRegex values to a boundMethod.
+func regexMethod(params int, function func(v *AstInterpreter, r *LoxRegex, arguments []interface{}) interface{}) *boundMethod {
+	return &boundMethod{params, func(v *AstInterpreter, receiver interface{}, arguments []interface{}) interface{} {
+		return function(v, receiver.(*LoxRegex), arguments)
+	}}
+}
+
+var regexMethods = map[string]*boundMethod{
+	"match": regexMethod(1, func(v *AstInterpreter, r *LoxRegex, arguments []interface{}) interface{} {
+		return r.Regexp.MatchString(v.StringArgument("match", arguments, 0))
+	}),
+	// find returns the first match with its groups, nil without a match
+	"find": regexMethod(1, func(v *AstInterpreter, r *LoxRegex, arguments []interface{}) interface{} {
+		s := v.StringArgument("find", arguments, 0)
+		indexes := r.Regexp.FindStringSubmatchIndex(s)
+		if indexes == nil {
+			return nil
+		}
+		return groups(s, indexes)
+	}),
+	"findAll": regexMethod(1, func(v *AstInterpreter, r *LoxRegex, arguments []interface{}) interface{} {
+		s := v.StringArgument("findAll", arguments, 0)
+		var matches []interface{}
+		for _, indexes := range r.Regexp.FindAllStringSubmatchIndex(s, -1) {
+			matches = append(matches, groups(s, indexes))
+		}
+		return NewLoxList(matches)
+	}),
+	// replace replaces every match, $1 or ${name} in the replacement
+	// stand for a group
+	"replace": regexMethod(2, func(v *AstInterpreter, r *LoxRegex, arguments []interface{}) interface{} {
+		s := v.StringArgument("replace", arguments, 0)
+		return r.Regexp.ReplaceAllString(s, v.StringArgument("replace", arguments, 1))
+	}),
+	"split": regexMethod(1, func(v *AstInterpreter, r *LoxRegex, arguments []interface{}) interface{} {
+		var parts []interface{}
+		for _, part := range r.Regexp.Split(v.StringArgument("split", arguments, 0), -1) {
+			parts = append(parts, part)
+		}
+		return NewLoxList(parts)
+	}),
+}
@@ -0,0 +1,29 @@
+package visitor
+
+import (
+	"sort"
+)
+
+// LoxTrait is a set of methods that classes mix in, they are bound to the
+// instances of those classes like their own methods.
+type LoxTrait struct {
+	Name    string
+	Methods map[string]*LoxFunction
+}
+
+func NewLoxTrait(name string) *LoxTrait {
+	return &LoxTrait{
+		Name:    name,
+		Methods: make(map[string]*LoxFunction),
+	}
+}
+
+// MethodNames returns the sorted names of the methods of the trait.
+func (t *LoxTrait) MethodNames() []string {
+	names := make([]string, 0, len(t.Methods))
+	for name := range t.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
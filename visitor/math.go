@@ -0,0 +1,138 @@
+package visitor
+
+import (
+	"math"
+
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/utils"
+)
+
+// Math is the math module. Functions keep integer arguments exact where
+// they can, random numbers come from the interpreter's Random so math.seed
+// makes them repeatable.
+var Math = &LoxModule{
+	Name: "math",
+	Members: map[string]interface{}{
+		"pi":  math.Pi,
+		"inf": math.Inf(1),
+		"nan": math.NaN(),
+
+		"sqrt":  floatFunction("sqrt", math.Sqrt),
+		"sin":   floatFunction("sin", math.Sin),
+		"cos":   floatFunction("cos", math.Cos),
+		"tan":   floatFunction("tan", math.Tan),
+		"log":   floatFunction("log", math.Log),
+		"exp":   floatFunction("exp", math.Exp),
+		"floor": roundingFunction("floor", math.Floor),
+		"ceil":  roundingFunction("ceil", math.Ceil),
+		"round": roundingFunction("round", math.Round),
+
+		"abs": &NativeFunction{
+			Name:   "math.abs",
+			Params: 1,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				x := v.NumberArgument("math.abs", arguments, 0)
+				if Compare(lexer.LESS, x, int64(0)) {
+					return Negate(x)
+				}
+				return x
+			},
+		},
+		"pow": &NativeFunction{
+			Name:   "math.pow",
+			Params: 2,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				x := v.NumberArgument("math.pow", arguments, 0)
+				y := v.NumberArgument("math.pow", arguments, 1)
+				return Arithmetic(lexer.STAR_STAR, x, y)
+			},
+		},
+		"min": &NativeFunction{
+			Name:   "math.min",
+			Params: 2,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				x := v.NumberArgument("math.min", arguments, 0)
+				y := v.NumberArgument("math.min", arguments, 1)
+				if Compare(lexer.LESS, y, x) {
+					return y
+				}
+				return x
+			},
+		},
+		"max": &NativeFunction{
+			Name:   "math.max",
+			Params: 2,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				x := v.NumberArgument("math.max", arguments, 0)
+				y := v.NumberArgument("math.max", arguments, 1)
+				if Compare(lexer.GREATER, y, x) {
+					return y
+				}
+				return x
+			},
+		},
+		"isNaN": &NativeFunction{
+			Name:   "math.isNaN",
+			Params: 1,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				x, ok := v.NumberArgument("math.isNaN", arguments, 0).(float64)
+				return ok && math.IsNaN(x)
+			},
+		},
+
+		"random": &NativeFunction{
+			Name:   "math.random",
+			Params: 0,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				return v.Random.Float64()
+			},
+		},
+		// randomInt returns an integer between its arguments, both included
+		"randomInt": &NativeFunction{
+			Name:   "math.randomInt",
+			Params: 2,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				min := v.IntegerArgument("math.randomInt", arguments, 0)
+				max := v.IntegerArgument("math.randomInt", arguments, 1)
+				if max < min || max-min+1 <= 0 {
+					panic(glox_error.NewRuntimeError(utils.INVALID_RANDOM_RANGE, v.CallSite()))
+				}
+				return min + v.Random.Int63n(max-min+1)
+			},
+		},
+		"seed": &NativeFunction{
+			Name:   "math.seed",
+			Params: 1,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				v.Random.Seed(v.IntegerArgument("math.seed", arguments, 0))
+				return nil
+			},
+		},
+	},
+}
+
+func floatFunction(name string, function func(float64) float64) *NativeFunction {
+	return &NativeFunction{
+		Name:   "math." + name,
+		Params: 1,
+		Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+			return function(toFloat(v.NumberArgument("math."+name, arguments, 0)))
+		},
+	}
+}
+
+// roundingFunction rounds floats, integers are already round.
+func roundingFunction(name string, function func(float64) float64) *NativeFunction {
+	return &NativeFunction{
+		Name:   "math." + name,
+		Params: 1,
+		Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+			x := v.NumberArgument("math."+name, arguments, 0)
+			if i, ok := x.(int64); ok {
+				return i
+			}
+			return function(x.(float64))
+		},
+	}
+}
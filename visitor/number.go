@@ -0,0 +1,238 @@
+package visitor
+
+import (
+	"math"
+
+	"github.com/jameslahm/glox/lexer"
+)
+
+// Lox numbers are float64, or int64 for integer literals and the results of
+// integer arithmetic that are exact. Integers are promoted to floats when an
+// operation overflows or its result isn't an integer, so both behave as the
+// same number type.
+
+func IsNumber(value interface{}) bool {
+	switch value.(type) {
+	case float64, int64:
+		return true
+	}
+	return false
+}
+
+func toFloat(value interface{}) float64 {
+	if i, ok := value.(int64); ok {
+		return float64(i)
+	}
+	return value.(float64)
+}
+
+func integers(a interface{}, b interface{}) (int64, int64, bool) {
+	x, ok := a.(int64)
+	if !ok {
+		return 0, 0, false
+	}
+	y, ok := b.(int64)
+	return x, y, ok
+}
+
+// Arithmetic applies one of + - * / % ~/ ** to two numbers. ~/ divides and
+// truncates the result towards zero, % is the remainder of that division.
+func Arithmetic(operator int, a interface{}, b interface{}) interface{} {
+	if x, y, ok := integers(a, b); ok {
+		if result, exact := integerArithmetic(operator, x, y); exact {
+			return result
+		}
+	}
+	x, y := toFloat(a), toFloat(b)
+	switch operator {
+	case lexer.PLUS:
+		return x + y
+	case lexer.MINUS:
+		return x - y
+	case lexer.STAR:
+		return x * y
+	case lexer.PERCENT:
+		return math.Mod(x, y)
+	case lexer.TILDE_SLASH:
+		return math.Trunc(x / y)
+	case lexer.STAR_STAR:
+		return math.Pow(x, y)
+	default:
+		return x / y
+	}
+}
+
+// integerArithmetic reports whether the result of the operation is an exact
+// 64-bit integer.
+func integerArithmetic(operator int, x int64, y int64) (int64, bool) {
+	switch operator {
+	case lexer.PLUS:
+		result := x + y
+		return result, (x^result)&(y^result) >= 0
+	case lexer.MINUS:
+		result := x - y
+		return result, (x^y)&(x^result) >= 0
+	case lexer.STAR:
+		if x == 0 || y == 0 {
+			return 0, true
+		}
+		result := x * y
+		return result, result/y == x && !(x == -1 && y == math.MinInt64) && !(y == -1 && x == math.MinInt64)
+	case lexer.PERCENT:
+		if y == 0 {
+			return 0, false
+		}
+		return x % y, true
+	case lexer.TILDE_SLASH:
+		if y == 0 || (x == math.MinInt64 && y == -1) {
+			return 0, false
+		}
+		return x / y, true
+	case lexer.STAR_STAR:
+		return integerPower(x, y)
+	default:
+		if y == 0 || (x == math.MinInt64 && y == -1) || x%y != 0 {
+			return 0, false
+		}
+		return x / y, true
+	}
+}
+
+func integerPower(x int64, y int64) (int64, bool) {
+	if y < 0 {
+		return 0, false
+	}
+	result := int64(1)
+	for ; y > 0; y >>= 1 {
+		var exact bool
+		if y&1 == 1 {
+			if result, exact = integerArithmetic(lexer.STAR, result, x); !exact {
+				return 0, false
+			}
+		}
+		if y > 1 {
+			if x, exact = integerArithmetic(lexer.STAR, x, x); !exact {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// ToInteger converts a number to an integer if it has an integer value.
+func ToInteger(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+// Bitwise applies one of & | ^ << >> to two integers, shifts are arithmetic
+// and the count must not be negative.
+func Bitwise(operator int, x int64, y int64) int64 {
+	switch operator {
+	case lexer.AMPERSAND:
+		return x & y
+	case lexer.PIPE:
+		return x | y
+	case lexer.CARET:
+		return x ^ y
+	case lexer.LESS_LESS:
+		return x << uint64(y)
+	default:
+		return x >> uint64(y)
+	}
+}
+
+// Negate returns -value of a number, -0 is a float.
+func Negate(value interface{}) interface{} {
+	if i, ok := value.(int64); ok && i != math.MinInt64 && i != 0 {
+		return -i
+	}
+	return -toFloat(value)
+}
+
+// Compare applies one of < <= > >= to two numbers, integers are compared
+// exactly, also with floats. Comparisons with NaN are false.
+func Compare(operator int, a interface{}, b interface{}) bool {
+	c, ok := compareNumbers(a, b)
+	if !ok {
+		return false
+	}
+	switch operator {
+	case lexer.LESS:
+		return c < 0
+	case lexer.LESS_EQUAL:
+		return c <= 0
+	case lexer.GREATER:
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// compareNumbers returns -1, 0 or 1 as a is less than, equal to or greater
+// than b, it reports false when one of them is NaN.
+func compareNumbers(a interface{}, b interface{}) (int, bool) {
+	if x, y, ok := integers(a, b); ok {
+		return compareIntegers(x, y), true
+	}
+	if x, ok := a.(int64); ok {
+		return compareIntegerFloat(x, b.(float64))
+	}
+	if y, ok := b.(int64); ok {
+		c, ok := compareIntegerFloat(y, a.(float64))
+		return -c, ok
+	}
+	x, y := a.(float64), b.(float64)
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	case x == y:
+		return 0, true
+	}
+	return 0, false
+}
+
+func compareIntegers(x int64, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// compareIntegerFloat compares an integer with a float without converting
+// the integer to a float, which would round integers above 2^53.
+func compareIntegerFloat(x int64, y float64) (int, bool) {
+	switch {
+	case math.IsNaN(y):
+		return 0, false
+	case y >= 1<<63:
+		return -1, true
+	case y < -1<<63:
+		return 1, true
+	}
+	// y is within the range of int64, compare its integer part exactly and
+	// then its fraction
+	whole := math.Trunc(y)
+	if c := compareIntegers(x, int64(whole)); c != 0 {
+		return c, true
+	}
+	switch {
+	case y > whole:
+		return -1, true
+	case y < whole:
+		return 1, true
+	}
+	return 0, true
+}
@@ -0,0 +1,95 @@
+package visitor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/utils"
+)
+
+// Exit unwinds the interpreter when a script calls exit, Interpret returns
+// it as its error. Token is the paren of the exit call.
+type Exit struct {
+	Code  int
+	Token lexer.Token
+}
+
+func (e *Exit) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// readLine reads a line from Stdin without its line ending, nil once the
+// input is exhausted.
+func (v *AstInterpreter) readLine() interface{} {
+	line, err := v.Stdin.ReadString('\n')
+	if err != nil && err != io.EOF {
+		panic(glox_error.NewRuntimeError(err.Error(), v.CallSite()))
+	}
+	if err == io.EOF && line == "" {
+		return nil
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
+
+// ReadLine returns the next line of the standard input.
+var ReadLine = &NativeFunction{
+	Name:   "readLine",
+	Params: 0,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		return v.readLine()
+	},
+}
+
+// Input prints a prompt and returns the next line of the standard input.
+var Input = &NativeFunction{
+	Name:   "input",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		fmt.Fprint(v.Stdout, Stringify(arguments[0]))
+		return v.readLine()
+	},
+}
+
+// Args returns the arguments given to the script as a list of strings.
+var Args = &NativeFunction{
+	Name:   "args",
+	Params: 0,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		elements := make([]interface{}, len(v.Args))
+		for i, arg := range v.Args {
+			elements[i] = arg
+		}
+		return NewLoxList(elements)
+	},
+}
+
+// Env returns the value of an environment variable, nil when it isn't set.
+var Env = &NativeFunction{
+	Name:   "env",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		value, ok := os.LookupEnv(v.StringArgument("env", arguments, 0))
+		if !ok {
+			return nil
+		}
+		return value
+	},
+}
+
+// ExitFunction stops the script with an exit status from 0 to 255, the
+// range processes can report.
+var ExitFunction = &NativeFunction{
+	Name:   "exit",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		code := v.IntegerArgument("exit", arguments, 0)
+		if code < 0 || code > 255 {
+			panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_ARGUMENT, 1, "exit", "an integer from 0 to 255"), v.CallSite()))
+		}
+		panic(&Exit{Code: int(code), Token: v.CallSite()})
+	},
+}
@@ -0,0 +1,193 @@
+package visitor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/utils"
+)
+
+// TypeName returns the name of the type of a Lox value as type returns it.
+func TypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case float64, int64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case *LoxClass:
+		return "class"
+	case *LoxTrait:
+		return "trait"
+	case *LoxInstance:
+		return "instance"
+	case *LoxList:
+		return "list"
+	case *LoxMap:
+		return "map"
+	case *LoxModule:
+		return "module"
+	case *LoxRegex:
+		return "regex"
+	case LoxCallable:
+		return "function"
+	default:
+		return "unknown"
+	}
+}
+
+// Type returns the name of the type of its argument.
+var Type = &NativeFunction{
+	Name:   "type",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		return TypeName(arguments[0])
+	},
+}
+
+// ClassName returns the name of the class of an instance.
+var ClassName = &NativeFunction{
+	Name:   "className",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		return v.InstanceArgument("className", arguments, 0).Class.Name
+	},
+}
+
+// HasField reports whether an instance has a field, methods and private
+// fields don't count.
+var HasField = &NativeFunction{
+	Name:   "hasField",
+	Params: 2,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		instance := v.InstanceArgument("hasField", arguments, 0)
+		name := v.StringArgument("hasField", arguments, 1)
+		_, ok := instance.Fields[name]
+		return ok && !IsPrivate(name)
+	},
+}
+
+// propertyArgument returns a call site token naming the property given as
+// argument 1 of native, private properties can't be reached by name.
+func (v *AstInterpreter) propertyArgument(native string, arguments []interface{}) lexer.Token {
+	name := v.CallSite()
+	name.Lexeme = v.StringArgument(native, arguments, 1)
+	if IsPrivate(name.Lexeme) {
+		panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.PRIVATE_PROPERTY, name.Lexeme), v.CallSite()))
+	}
+	return name
+}
+
+// GetField reads a property whose name is only known at runtime, like a
+// property access it runs getters and falls back to the methods of the
+// class.
+var GetField = &NativeFunction{
+	Name:   "getField",
+	Params: 2,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		instance := v.InstanceArgument("getField", arguments, 0)
+		return v.GetProperty(instance, v.propertyArgument("getField", arguments))
+	},
+}
+
+// SetField assigns a property whose name is only known at runtime, running
+// its setter if there is one, and returns the value.
+var SetField = &NativeFunction{
+	Name:   "setField",
+	Params: 3,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		instance := v.InstanceArgument("setField", arguments, 0)
+		v.SetProperty(instance, v.propertyArgument("setField", arguments), arguments[2])
+		return arguments[2]
+	},
+}
+
+func sortedNames(names []string) *LoxList {
+	sort.Strings(names)
+	elements := make([]interface{}, len(names))
+	for i, name := range names {
+		elements[i] = name
+	}
+	return NewLoxList(elements)
+}
+
+// Fields returns the sorted names of the public fields of an instance.
+var Fields = &NativeFunction{
+	Name:   "fields",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		return sortedNames(v.InstanceArgument("fields", arguments, 0).PublicFields())
+	},
+}
+
+// Methods returns the sorted names of the public methods of a class,
+// including the ones of its traits and the ones it inherits.
+var Methods = &NativeFunction{
+	Name:   "methods",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		seen := make(map[string]bool)
+		var names []string
+		add := func(methods map[string]*LoxFunction) {
+			for name := range methods {
+				if !seen[name] && !IsPrivate(name) {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+		for class := v.ClassArgument("methods", arguments, 0); class != nil; class = class.SuperClass {
+			add(class.Methods)
+			for _, trait := range class.Traits {
+				add(trait.Methods)
+			}
+		}
+		return sortedNames(names)
+	},
+}
+
+// Superclass returns the superclass of a class, nil when it has none.
+var Superclass = &NativeFunction{
+	Name:   "superclass",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		if superClass := v.ClassArgument("superclass", arguments, 0).SuperClass; superClass != nil {
+			return superClass
+		}
+		return nil
+	},
+}
+
+// InstanceOf reports whether a value is an instance of a class or of one
+// of its subclasses, or of a class mixing in a trait.
+var InstanceOf = &NativeFunction{
+	Name:   "instanceof",
+	Params: 2,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		trait, isTrait := arguments[1].(*LoxTrait)
+		var target *LoxClass
+		if !isTrait {
+			target = v.ClassArgument("instanceof", arguments, 1)
+		}
+		instance, ok := arguments[0].(*LoxInstance)
+		if !ok {
+			return false
+		}
+		for class := instance.Class; class != nil; class = class.SuperClass {
+			if class == target {
+				return true
+			}
+			for _, t := range class.Traits {
+				if isTrait && t == trait {
+					return true
+				}
+			}
+		}
+		return false
+	},
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/environment"
 	"github.com/jameslahm/glox/lexer"
 	"github.com/jameslahm/glox/utils"
 )
@@ -15,6 +16,12 @@ const (
 	None
 	Class
 	SubClass
+	// ClassMethod is the class type inside the class methods of a class,
+	// which have neither this nor super
+	ClassMethod
+	// Trait is the class type inside the methods of a trait, which have
+	// this but no super
+	Trait
 )
 
 type Resolver struct {
@@ -23,6 +30,8 @@ type Resolver struct {
 	VariableBindingDistances map[ast.Node]int
 	InFunctionType           int
 	InClassType              int
+	// PrivateNames are the #names declared by the enclosing class
+	PrivateNames map[string]bool
 }
 
 func NewResolver() *Resolver {
@@ -36,6 +45,36 @@ func NewResolver() *Resolver {
 	}
 }
 
+// NewEnvResolver returns a resolver whose scopes mirror the chain of env, so
+// code can be resolved against an environment that is already running.
+func NewEnvResolver(env *environment.Env) *Resolver {
+	resolver := NewResolver()
+	resolver.Scopes = nil
+	for e := env; e != nil; e = e.Parent {
+		scope := make(map[string]bool)
+		for name := range e.Values {
+			scope[name] = true
+		}
+		if scope["this"] && resolver.InClassType == None {
+			resolver.InClassType = Class
+			if instance, ok := e.Values["this"].(*LoxInstance); ok {
+				resolver.PrivateNames = instance.PrivateNames()
+			}
+		}
+		if scope["super"] {
+			resolver.InClassType = SubClass
+		}
+		resolver.Scopes = append([]map[string]bool{scope}, resolver.Scopes...)
+	}
+	return resolver
+}
+
+func (v *Resolver) Error(token lexer.Token, message string) {
+	err := errors.New(utils.FormatError(token.Line, fmt.Sprintf(" at '%s'", token.Lexeme), message))
+	v.Errors = append(v.Errors, err)
+	utils.Report(err)
+}
+
 func (v *Resolver) VisitBlockStatement(node *ast.BlockStatement) interface{} {
 	v.EnterScope()
 	for _, statement := range node.Statements {
@@ -57,9 +96,7 @@ func (v *Resolver) VisitVarDeclaration(node *ast.VarDeclaration) interface{} {
 func (v *Resolver) Declare(token lexer.Token) {
 	scope := v.GetCurrentScope()
 	if _, ok := scope[token.Lexeme]; ok {
-		err := errors.New(utils.ALREADY_DECLARE_VARIABLE)
-		v.Errors = append(v.Errors, err)
-		utils.Error(token.Line, err.Error())
+		v.Error(token, utils.ALREADY_DECLARE_VARIABLE)
 	}
 	scope[token.Lexeme] = false
 }
@@ -85,9 +122,7 @@ func (v *Resolver) GetCurrentScope() map[string]bool {
 func (v *Resolver) VisitVariable(node *ast.Variable) interface{} {
 	scope := v.GetCurrentScope()
 	if value, ok := scope[node.Name.Lexeme]; ok && !value {
-		err := errors.New(utils.WARN_READ_VARIABLE_BEFORE_DEFINE)
-		v.Errors = append(v.Errors, err)
-		utils.Error(node.Name.Line, err.Error())
+		v.Error(node.Name, utils.WARN_READ_VARIABLE_BEFORE_DEFINE)
 	}
 	v.Resolve(node, node.Name.Lexeme)
 
@@ -113,14 +148,21 @@ func (v *Resolver) Resolve(node ast.Node, name string) {
 func (v *Resolver) VisitFuncDeclaration(node *ast.FuncDeclaration) interface{} {
 	v.Declare(node.Name)
 	v.Define(node.Name)
+	v.ResolveFunction(node)
+	return nil
+}
 
+// ResolveFunction resolves the parameters and body of a function, methods
+// are resolved without declaring their name.
+func (v *Resolver) ResolveFunction(node *ast.FuncDeclaration) {
 	v.EnterScope()
 	for _, param := range node.Params {
 		v.Declare(param)
 		v.Define(param)
 	}
 	inFunctionTypeBackup := v.InFunctionType
-	if v.InClassType != None && node.Name.Lexeme == "init" {
+	// Class methods named init are ordinary functions
+	if v.InClassType != None && v.InClassType != ClassMethod && node.Name.Lexeme == "init" {
 		v.InFunctionType = FunctionInit
 	} else {
 		v.InFunctionType = FunctionNormal
@@ -128,14 +170,13 @@ func (v *Resolver) VisitFuncDeclaration(node *ast.FuncDeclaration) interface{} {
 	node.Body.Accept(v)
 	v.InFunctionType = inFunctionTypeBackup
 	v.ExitScope()
-	return nil
 }
 
 func (v *Resolver) VisitThisExpr(node *ast.ThisExpr) interface{} {
-	if v.InClassType != Class {
-		err := errors.New(utils.WARN_USE_THIS_OUT_CLASS)
-		v.Errors = append(v.Errors, err)
-		utils.Error(node.Keyword.Line, err.Error())
+	if v.InClassType == None {
+		v.Error(node.Keyword, utils.WARN_USE_THIS_OUT_CLASS)
+	} else if v.InClassType == ClassMethod {
+		v.Error(node.Keyword, utils.WARN_USE_THIS_IN_CLASS_METHOD)
 	}
 	v.Resolve(node, node.Keyword.Lexeme)
 	return nil
@@ -162,16 +203,12 @@ func (v *Resolver) VisitPrintStatement(node *ast.PrintStatement) interface{} {
 
 func (v *Resolver) VisitReturnStatement(node *ast.ReturnStatement) interface{} {
 	if v.InFunctionType == None {
-		err := errors.New(utils.WARN_RETURN_FROM_NOFUNCTION)
-		v.Errors = append(v.Errors, err)
-		utils.Error(node.Keyword.Line, err.Error())
+		v.Error(node.Keyword, utils.WARN_RETURN_FROM_NOFUNCTION)
 	}
 
 	if node.Expr != nil {
 		if v.InFunctionType == FunctionInit {
-			err := errors.New(utils.WARN_RETURN_VALUE_FROM_INIT)
-			v.Errors = append(v.Errors, err)
-			utils.Error(node.Keyword.Line, err.Error())
+			v.Error(node.Keyword, utils.WARN_RETURN_VALUE_FROM_INIT)
 		}
 		node.Expr.Accept(v)
 	}
@@ -184,6 +221,16 @@ func (v *Resolver) VisitWhileStatement(node *ast.WhileStatement) interface{} {
 	return nil
 }
 
+func (v *Resolver) VisitTryStatement(node *ast.TryStatement) interface{} {
+	node.Body.Accept(v)
+	v.EnterScope()
+	v.Declare(node.Name)
+	v.Define(node.Name)
+	node.Catch.Accept(v)
+	v.ExitScope()
+	return nil
+}
+
 func (v *Resolver) VisitBinaryExpr(node *ast.BinaryExpr) interface{} {
 	node.Left.Accept(v)
 	node.Right.Accept(v)
@@ -208,12 +255,32 @@ func (v *Resolver) VisitLiteralExpr(node *ast.LiteralExpr) interface{} {
 	return nil
 }
 
+func (v *Resolver) VisitInterpolationExpr(node *ast.InterpolationExpr) interface{} {
+	for _, part := range node.Parts {
+		part.Accept(v)
+	}
+	return nil
+}
+
 func (v *Resolver) VisitLogicalExpr(node *ast.LogicalExpr) interface{} {
 	node.Left.Accept(v)
 	node.Right.Accept(v)
 	return nil
 }
 
+func (v *Resolver) VisitIndexExpr(node *ast.IndexExpr) interface{} {
+	node.Expr.Accept(v)
+	node.Index.Accept(v)
+	return nil
+}
+
+func (v *Resolver) VisitConditionalExpr(node *ast.ConditionalExpr) interface{} {
+	node.Condition.Accept(v)
+	node.Then.Accept(v)
+	node.Else.Accept(v)
+	return nil
+}
+
 func (v *Resolver) VisitUnaryExpr(node *ast.UnaryExpr) interface{} {
 	node.Right.Accept(v)
 	return nil
@@ -229,63 +296,140 @@ func (v *Resolver) VisitProgram(node *ast.Program) interface{} {
 func (v *Resolver) VisitClassDeclaration(node *ast.ClassDeclaration) interface{} {
 
 	inClassTypeBackUp := v.InClassType
+	privateNamesBackup := v.PrivateNames
 
 	v.InClassType = Class
+	v.PrivateNames = make(map[string]bool)
+	for _, field := range node.Fields {
+		if field.Name.Type == lexer.PRIVATE_IDENTIFIER {
+			v.PrivateNames[field.Name.Lexeme] = true
+		}
+	}
+	for _, method := range node.Methods {
+		if method.Name.Type == lexer.PRIVATE_IDENTIFIER {
+			v.PrivateNames[method.Name.Lexeme] = true
+		}
+	}
 	v.Declare(node.Name)
 	v.Define(node.Name)
 
 	if node.SuperClass != nil {
 		if node.SuperClass.Name.Lexeme == node.Name.Lexeme {
-			err := errors.New(utils.WARN_INHERIT_FROM_SELF)
-			v.Errors = append(v.Errors, err)
-			fmt.Println(err)
+			v.Error(node.SuperClass.Name, utils.WARN_INHERIT_FROM_SELF)
 		}
 
-		v.InClassType = SubClass
-
 		node.SuperClass.Accept(v)
+	}
+	for _, trait := range node.Traits {
+		trait.Accept(v)
+	}
 
+	// Traits give a class a super even without a superclass
+	hasSuper := node.SuperClass != nil || len(node.Traits) != 0
+	if hasSuper {
+		v.InClassType = SubClass
 		v.EnterScope()
 		scope := v.GetCurrentScope()
 		scope["super"] = true
 	}
 
+	// Class methods aren't bound to an instance, so they close over the
+	// scope of the class without this
+	classType := v.InClassType
+	v.InClassType = ClassMethod
+	for _, method := range node.Methods {
+		if method.Kind == ast.ClassMethodKind {
+			v.ResolveFunction(method)
+		}
+	}
+	v.InClassType = classType
+
 	v.EnterScope()
 
 	scope := v.GetCurrentScope()
 	scope["this"] = true
+	for _, field := range node.Fields {
+		if field.Initializer != nil {
+			field.Initializer.Accept(v)
+		}
+	}
 	for _, method := range node.Methods {
-		method.Accept(v)
+		if method.Kind != ast.ClassMethodKind {
+			v.ResolveFunction(method)
+		}
 	}
 
 	v.ExitScope()
-	if node.SuperClass != nil {
+	if hasSuper {
 		v.ExitScope()
 	}
 	v.InClassType = inClassTypeBackUp
+	v.PrivateNames = privateNamesBackup
+	return nil
+}
+
+func (v *Resolver) VisitTraitDeclaration(node *ast.TraitDeclaration) interface{} {
+	v.Declare(node.Name)
+	v.Define(node.Name)
+
+	inClassTypeBackUp := v.InClassType
+	privateNamesBackup := v.PrivateNames
+	v.InClassType = Trait
+	// Traits declare no private names, so they can't use the ones of the
+	// classes mixing them in
+	v.PrivateNames = nil
+
+	v.EnterScope()
+	scope := v.GetCurrentScope()
+	scope["this"] = true
+	for _, method := range node.Methods {
+		if method.Name.Lexeme == "init" {
+			v.Error(method.Name, utils.WARN_INIT_IN_TRAIT)
+		}
+		v.ResolveFunction(method)
+	}
+	v.ExitScope()
+
+	v.InClassType = inClassTypeBackUp
+	v.PrivateNames = privateNamesBackup
 	return nil
 }
 
 func (v *Resolver) VisitGetExpr(node *ast.GetExpr) interface{} {
 	node.Expr.Accept(v)
+	v.ResolvePrivate(node.Expr, node.Name)
 	return nil
 }
 
 func (v *Resolver) VisitSetExpr(node *ast.SetExpr) interface{} {
 	node.Expr.Accept(v)
+	v.ResolvePrivate(node.Expr, node.Name)
 	node.Value.Accept(v)
 	return nil
 }
 
+// ResolvePrivate checks that a private property is accessed through this
+// and declared by the enclosing class.
+func (v *Resolver) ResolvePrivate(object ast.Node, name lexer.Token) {
+	if name.Type != lexer.PRIVATE_IDENTIFIER {
+		return
+	}
+	if _, ok := object.(*ast.ThisExpr); !ok {
+		v.Error(name, fmt.Sprintf(utils.WARN_PRIVATE_OUTSIDE_THIS, name.Lexeme))
+	} else if !v.PrivateNames[name.Lexeme] {
+		v.Error(name, fmt.Sprintf(utils.WARN_UNDECLARED_PRIVATE, name.Lexeme))
+	}
+}
+
 func (v *Resolver) VisitSuperExpr(node *ast.SuperExpr) interface{} {
 	if v.InClassType == None {
-		err := errors.New(utils.WARN_USE_SUPER_OUT_CLASS)
-		v.Errors = append(v.Errors, err)
-		fmt.Println(err)
+		v.Error(node.Keyword, utils.WARN_USE_SUPER_OUT_CLASS)
 	} else if v.InClassType == Class {
-		err := errors.New(utils.WARN_USE_SUPER_OUT_SUBCLASS)
-		v.Errors = append(v.Errors, err)
-		fmt.Println(err)
+		v.Error(node.Keyword, utils.WARN_USE_SUPER_OUT_SUBCLASS)
+	} else if v.InClassType == ClassMethod {
+		v.Error(node.Keyword, utils.WARN_USE_SUPER_IN_CLASS_METHOD)
+	} else if v.InClassType == Trait {
+		v.Error(node.Keyword, utils.WARN_USE_SUPER_IN_TRAIT)
 	}
 	v.Resolve(node, node.Keyword.Lexeme)
 	return nil
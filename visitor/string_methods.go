@@ -0,0 +1,91 @@
+package visitor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/utils"
+)
+
+// stringMethod adapts a method of string values to a boundMethod, indexes
+// and lengths count Unicode code points rather than bytes.
+func stringMethod(params int, function func(v *AstInterpreter, s string, arguments []interface{}) interface{}) *boundMethod {
+	return &boundMethod{params, func(v *AstInterpreter, receiver interface{}, arguments []interface{}) interface{} {
+		return function(v, receiver.(string), arguments)
+	}}
+}
+
+var stringMethods = map[string]*boundMethod{
+	"len": stringMethod(0, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		return int64(utf8.RuneCountInString(s))
+	}),
+	"upper": stringMethod(0, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		return strings.ToUpper(s)
+	}),
+	"lower": stringMethod(0, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		return strings.ToLower(s)
+	}),
+	"trim": stringMethod(0, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		return strings.TrimSpace(s)
+	}),
+	"split": stringMethod(1, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		var parts []interface{}
+		for _, part := range strings.Split(s, v.StringArgument("split", arguments, 0)) {
+			parts = append(parts, part)
+		}
+		return NewLoxList(parts)
+	}),
+	"contains": stringMethod(1, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		return strings.Contains(s, v.StringArgument("contains", arguments, 0))
+	}),
+	"startsWith": stringMethod(1, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		return strings.HasPrefix(s, v.StringArgument("startsWith", arguments, 0))
+	}),
+	"endsWith": stringMethod(1, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		return strings.HasSuffix(s, v.StringArgument("endsWith", arguments, 0))
+	}),
+	"indexOf": stringMethod(1, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		index := strings.Index(s, v.StringArgument("indexOf", arguments, 0))
+		if index < 0 {
+			return int64(-1)
+		}
+		return int64(utf8.RuneCountInString(s[:index]))
+	}),
+	"replace": stringMethod(2, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		old := v.StringArgument("replace", arguments, 0)
+		return strings.Replace(s, old, v.StringArgument("replace", arguments, 1), -1)
+	}),
+	"substring": stringMethod(2, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		runes := []rune(s)
+		start := v.IntegerArgument("substring", arguments, 0)
+		end := v.IntegerArgument("substring", arguments, 1)
+		if start < 0 || end > int64(len(runes)) || start > end {
+			panic(glox_error.NewRuntimeError(utils.INDEX_OUT_OF_RANGE, v.CallSite()))
+		}
+		return string(runes[start:end])
+	}),
+	"repeat": stringMethod(1, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		count := v.IntegerArgument("repeat", arguments, 0)
+		if count < 0 {
+			panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_ARGUMENT, 1, "repeat", "a non-negative integer"), v.CallSite()))
+		}
+		// Dividing instead of multiplying keeps the check from overflowing
+		if len(s) > 0 && count > maxStringLength/int64(len(s)) {
+			panic(glox_error.NewRuntimeError(utils.STRING_TOO_LONG, v.CallSite()))
+		}
+		return strings.Repeat(s, int(count))
+	}),
+	"chars": stringMethod(0, func(v *AstInterpreter, s string, arguments []interface{}) interface{} {
+		var chars []interface{}
+		for _, c := range s {
+			chars = append(chars, string(c))
+		}
+		return NewLoxList(chars)
+	}),
+}
+
+// maxStringLength is the length in bytes of the longest string repeat
+// builds.
+const maxStringLength = 1 << 30
@@ -0,0 +1,88 @@
+package visitor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// formatFloat writes a float in decimal notation, except for very large and
+// very small magnitudes which use an exponent like JavaScript does.
+func formatFloat(f float64) string {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	return strconv.FormatFloat(f, format, -1, 64)
+}
+
+// Stringify formats a value the way Lox prints it.
+func Stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return formatFloat(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case *LoxFunction:
+		return fmt.Sprintf("<fn %s>", v.Node.Name.Lexeme)
+	case *LoxClass:
+		return v.Name
+	case *LoxTrait:
+		return fmt.Sprintf("<trait %s>", v.Name)
+	case *LoxInstance:
+		return fmt.Sprintf("%s instance", v.Class.Name)
+	case *LoxList:
+		return v.String()
+	case *LoxMap:
+		return v.String()
+	case *LoxRegex:
+		return v.String()
+	case *LoxModule:
+		return fmt.Sprintf("<module %s>", v.Name)
+	case LoxCallable:
+		return "<native fn>"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// Inspect is Stringify with strings quoted, so they can be told apart from
+// other values in messages.
+func Inspect(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return Stringify(value)
+}
+
+// IsTruthy reports whether a value counts as true in a condition, only nil
+// and false are falsy.
+func IsTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	default:
+		return true
+	}
+}
+
+// IsEqual reports whether two Lox values are equal, an integer equals the
+// float with the same value and NaN equals nothing, not even itself.
+func IsEqual(a interface{}, b interface{}) bool {
+	if x, y, ok := integers(a, b); ok {
+		return x == y
+	}
+	if IsNumber(a) && IsNumber(b) {
+		c, ok := compareNumbers(a, b)
+		return ok && c == 0
+	}
+	return a == b
+}
@@ -0,0 +1,156 @@
+package visitor
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jameslahm/glox/glox_error"
+	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/utils"
+)
+
+// TimeSource is where the time natives get the time from, tests replace
+// the system time with a ManualTime to be deterministic. Dates are
+// formatted and parsed in the location of the times Now returns.
+type TimeSource interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type systemTime struct{}
+
+func (systemTime) Now() time.Time {
+	return time.Now()
+}
+
+func (systemTime) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// SystemTime is the real time in the local time zone.
+var SystemTime TimeSource = systemTime{}
+
+// ManualTime is a TimeSource that stands still, sleeping moves it forward
+// without waiting.
+type ManualTime struct {
+	Time time.Time
+}
+
+func NewManualTime(t time.Time) *ManualTime {
+	return &ManualTime{Time: t}
+}
+
+func (m *ManualTime) Now() time.Time {
+	return m.Time
+}
+
+func (m *ManualTime) Sleep(d time.Duration) {
+	m.Time = m.Time.Add(d)
+}
+
+// Timestamps and durations are Lox numbers of milliseconds.
+func toMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// fromMilliseconds keeps integer timestamps exact, they don't fit the
+// precision of a float once converted to nanoseconds.
+func fromMilliseconds(v *AstInterpreter, value interface{}) time.Time {
+	var t time.Time
+	if ms, ok := ToInteger(value); ok {
+		t = time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
+	} else {
+		seconds := math.Floor(toFloat(value) / 1000)
+		t = time.Unix(int64(seconds), int64((toFloat(value)-seconds*1000)*float64(time.Millisecond)))
+	}
+	return t.In(v.Time.Now().Location())
+}
+
+// Clock returns the seconds since the Unix epoch with sub-second precision.
+var Clock = &NativeFunction{
+	Name:   "clock",
+	Params: 0,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		now := v.Time.Now()
+		return float64(now.Unix()) + float64(now.Nanosecond())/float64(time.Second)
+	},
+}
+
+// Now returns the current time as milliseconds since the Unix epoch.
+var Now = &NativeFunction{
+	Name:   "now",
+	Params: 0,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		return toMilliseconds(v.Time.Now())
+	},
+}
+
+// Sleep pauses the script for a number of milliseconds.
+var Sleep = &NativeFunction{
+	Name:   "sleep",
+	Params: 1,
+	Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+		ms := toFloat(v.NumberArgument("sleep", arguments, 0))
+		if ms > 0 {
+			v.Time.Sleep(time.Duration(ms * float64(time.Millisecond)))
+		}
+		return nil
+	},
+}
+
+// durationFunction converts a number of unit to milliseconds.
+func durationFunction(name string, unit time.Duration) *NativeFunction {
+	name = "time." + name
+	return &NativeFunction{
+		Name:   name,
+		Params: 1,
+		Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+			return Arithmetic(lexer.STAR, v.NumberArgument(name, arguments, 0), int64(unit/time.Millisecond))
+		},
+	}
+}
+
+// Time is the time module, layouts are Go reference layouts such as
+// "2006-01-02 15:04:05".
+var Time = &LoxModule{
+	Name: "time",
+	Members: map[string]interface{}{
+		"iso":  time.RFC3339,
+		"date": "2006-01-02",
+		"format": &NativeFunction{
+			Name:   "time.format",
+			Params: 2,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				t := fromMilliseconds(v, v.NumberArgument("time.format", arguments, 0))
+				return t.Format(v.StringArgument("time.format", arguments, 1))
+			},
+		},
+		"parse": &NativeFunction{
+			Name:   "time.parse",
+			Params: 2,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				text := v.StringArgument("time.parse", arguments, 0)
+				layout := v.StringArgument("time.parse", arguments, 1)
+				t, err := time.ParseInLocation(layout, text, v.Time.Now().Location())
+				if err != nil {
+					panic(glox_error.NewRuntimeError(fmt.Sprintf(utils.INVALID_TIME, text, layout), v.CallSite()))
+				}
+				return toMilliseconds(t)
+			},
+		},
+		"seconds": durationFunction("seconds", time.Second),
+		"minutes": durationFunction("minutes", time.Minute),
+		"hours":   durationFunction("hours", time.Hour),
+		"days":    durationFunction("days", 24*time.Hour),
+		// formatDuration writes a duration in milliseconds like 1h30m0s
+		"formatDuration": &NativeFunction{
+			Name:   "time.formatDuration",
+			Params: 1,
+			Function: func(v *AstInterpreter, arguments []interface{}) interface{} {
+				ms := toFloat(v.NumberArgument("time.formatDuration", arguments, 0))
+				return time.Duration(ms * float64(time.Millisecond)).String()
+			},
+		},
+	},
+}
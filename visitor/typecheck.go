@@ -0,0 +1,667 @@
+package visitor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/utils"
+)
+
+const (
+	TypeAny = iota
+	TypeNil
+	TypeNumber
+	TypeString
+	TypeBool
+	TypeFunction
+	TypeClass
+	TypeInstance
+)
+
+// LoxType is the static type of an expression. Unannotated code is typed as
+// TypeAny, which is compatible with everything, so untyped programs check
+// without errors.
+type LoxType struct {
+	Kind int
+
+	// Function signature, Params is nil for an unknown signature
+	Params []*LoxType
+	Return *LoxType
+
+	// Class and Instance
+	Class *ClassType
+}
+
+type ClassType struct {
+	Name string
+	// Fields include the properties of getters and setters
+	Fields       map[string]*LoxType
+	Methods      map[string]*LoxType
+	ClassMethods map[string]*LoxType
+	SuperClass   *ClassType
+}
+
+var (
+	AnyType    = &LoxType{Kind: TypeAny}
+	NilType    = &LoxType{Kind: TypeNil}
+	NumberType = &LoxType{Kind: TypeNumber}
+	StringType = &LoxType{Kind: TypeString}
+	BoolType   = &LoxType{Kind: TypeBool}
+)
+
+func (t *LoxType) String() string {
+	switch t.Kind {
+	case TypeNil:
+		return "nil"
+	case TypeNumber:
+		return "number"
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	case TypeFunction:
+		if t.Params == nil {
+			return "function"
+		}
+		var params []string
+		for _, param := range t.Params {
+			params = append(params, param.String())
+		}
+		return fmt.Sprintf("fun(%s): %s", strings.Join(params, ", "), t.Return)
+	case TypeClass:
+		return fmt.Sprintf("class %s", t.Class.Name)
+	case TypeInstance:
+		return t.Class.Name
+	default:
+		return "any"
+	}
+}
+
+// AssignableFrom reports whether a value of type source can be stored in a
+// location of type t.
+func (t *LoxType) AssignableFrom(source *LoxType) bool {
+	if t.Kind == TypeAny || source.Kind == TypeAny {
+		return true
+	}
+	if t.Kind == TypeInstance {
+		if source.Kind == TypeNil {
+			return true
+		}
+		return source.Kind == TypeInstance && source.Class.IsSubClassOf(t.Class)
+	}
+	if t.Kind == TypeClass {
+		return source.Kind == TypeClass && source.Class.IsSubClassOf(t.Class)
+	}
+	return t.Kind == source.Kind
+}
+
+func (c *ClassType) IsSubClassOf(other *ClassType) bool {
+	for class := c; class != nil; class = class.SuperClass {
+		if class == other {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ClassType) FindField(name string) *LoxType {
+	for class := c; class != nil; class = class.SuperClass {
+		if t, ok := class.Fields[name]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+func (c *ClassType) FindMethod(name string) *LoxType {
+	for class := c; class != nil; class = class.SuperClass {
+		if t, ok := class.Methods[name]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+func (c *ClassType) FindClassMethod(name string) *LoxType {
+	for class := c; class != nil; class = class.SuperClass {
+		if t, ok := class.ClassMethods[name]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// TypeChecker walks the ast and reports type mismatches between annotated
+// declarations and the types it infers for expressions. It never changes the
+// program, the interpreter runs annotated code exactly like untyped code.
+type TypeChecker struct {
+	Scopes  []map[string]*LoxType
+	Classes map[string]*ClassType
+	// Traits hold the method types of the traits by name
+	Traits       map[string]map[string]*LoxType
+	Errors       []error
+	ReturnTypes  []*LoxType
+	CurrentClass *ClassType
+}
+
+func NewTypeChecker() *TypeChecker {
+	return &TypeChecker{
+		Scopes:  []map[string]*LoxType{{}},
+		Classes: make(map[string]*ClassType),
+		Traits:  make(map[string]map[string]*LoxType),
+	}
+}
+
+func (v *TypeChecker) Error(token lexer.Token, message string) {
+	err := fmt.Errorf("[line %d:%d] Type error: %s", token.Line, token.Column, message)
+	v.Errors = append(v.Errors, err)
+	utils.Report(err)
+}
+
+func (v *TypeChecker) EnterScope() {
+	v.Scopes = append(v.Scopes, make(map[string]*LoxType))
+}
+
+func (v *TypeChecker) ExitScope() {
+	v.Scopes = v.Scopes[:len(v.Scopes)-1]
+}
+
+func (v *TypeChecker) Define(name string, t *LoxType) {
+	v.Scopes[len(v.Scopes)-1][name] = t
+}
+
+func (v *TypeChecker) Lookup(name string) *LoxType {
+	for i := len(v.Scopes) - 1; i >= 0; i-- {
+		if t, ok := v.Scopes[i][name]; ok {
+			return t
+		}
+	}
+	return AnyType
+}
+
+// ResolveAnnotation turns a written annotation into a type, a missing
+// annotation means any.
+func (v *TypeChecker) ResolveAnnotation(annotation *ast.TypeAnnotation) *LoxType {
+	if annotation == nil {
+		return AnyType
+	}
+	switch annotation.Name.Lexeme {
+	case "any":
+		return AnyType
+	case "nil":
+		return NilType
+	case "number":
+		return NumberType
+	case "string":
+		return StringType
+	case "bool":
+		return BoolType
+	case "function":
+		return &LoxType{Kind: TypeFunction}
+	}
+	if class, ok := v.Classes[annotation.Name.Lexeme]; ok {
+		return &LoxType{Kind: TypeInstance, Class: class}
+	}
+	v.Error(annotation.Name, fmt.Sprintf(utils.UNKNOWN_TYPE, annotation.Name.Lexeme))
+	return AnyType
+}
+
+func (v *TypeChecker) FunctionType(node *ast.FuncDeclaration) *LoxType {
+	params := []*LoxType{}
+	for i := range node.Params {
+		var annotation *ast.TypeAnnotation
+		if i < len(node.ParamTypes) {
+			annotation = node.ParamTypes[i]
+		}
+		params = append(params, v.ResolveAnnotation(annotation))
+	}
+	return &LoxType{
+		Kind:   TypeFunction,
+		Params: params,
+		Return: v.ResolveAnnotation(node.ReturnType),
+	}
+}
+
+func (v *TypeChecker) Check(node ast.Node) *LoxType {
+	if t, ok := node.Accept(v).(*LoxType); ok {
+		return t
+	}
+	return AnyType
+}
+
+func (v *TypeChecker) VisitProgram(node *ast.Program) interface{} {
+	// Register classes up front so annotations may reference classes
+	// declared later in the file.
+	for _, statement := range node.Statements {
+		if class, ok := statement.(*ast.ClassDeclaration); ok {
+			v.DeclareClass(class)
+		}
+	}
+	for _, statement := range node.Statements {
+		statement.Accept(v)
+	}
+	return nil
+}
+
+func (v *TypeChecker) DeclareClass(node *ast.ClassDeclaration) *ClassType {
+	if class, ok := v.Classes[node.Name.Lexeme]; ok {
+		return class
+	}
+	class := &ClassType{
+		Name:         node.Name.Lexeme,
+		Fields:       make(map[string]*LoxType),
+		Methods:      make(map[string]*LoxType),
+		ClassMethods: make(map[string]*LoxType),
+	}
+	v.Classes[node.Name.Lexeme] = class
+	return class
+}
+
+func (v *TypeChecker) VisitClassDeclaration(node *ast.ClassDeclaration) interface{} {
+	class := v.DeclareClass(node)
+	if node.SuperClass != nil {
+		if superClass, ok := v.Classes[node.SuperClass.Name.Lexeme]; ok && superClass != class {
+			class.SuperClass = superClass
+		}
+	}
+	for _, field := range node.Fields {
+		class.Fields[field.Name.Lexeme] = v.ResolveAnnotation(field.Type)
+	}
+	for _, method := range node.Methods {
+		name := method.Name.Lexeme
+		switch method.Kind {
+		case ast.ClassMethodKind:
+			class.ClassMethods[name] = v.FunctionType(method)
+		case ast.GetterKind:
+			// Getters have no annotation, the parameter of a setter types
+			// the property
+			if _, ok := class.Fields[name]; !ok {
+				class.Fields[name] = AnyType
+			}
+		case ast.SetterKind:
+			if len(method.ParamTypes) == 1 {
+				class.Fields[name] = v.ResolveAnnotation(method.ParamTypes[0])
+			}
+		default:
+			class.Methods[name] = v.FunctionType(method)
+		}
+	}
+	// Trait methods come before the ones of the superclass
+	for _, trait := range node.Traits {
+		for name, t := range v.Traits[trait.Name.Lexeme] {
+			if _, ok := class.Methods[name]; !ok {
+				class.Methods[name] = t
+			}
+		}
+	}
+	v.Define(node.Name.Lexeme, &LoxType{Kind: TypeClass, Class: class})
+
+	currentClassBackup := v.CurrentClass
+	v.CurrentClass = class
+	for _, field := range node.Fields {
+		if field.Initializer == nil {
+			continue
+		}
+		declared := class.Fields[field.Name.Lexeme]
+		if valueType := v.Check(field.Initializer); !declared.AssignableFrom(valueType) {
+			v.Error(field.Name, fmt.Sprintf(utils.INVALID_FIELD_TYPE, field.Name.Lexeme, declared, valueType))
+		}
+	}
+	for _, method := range node.Methods {
+		// Class methods have no this
+		v.CurrentClass = class
+		if method.Kind == ast.ClassMethodKind {
+			v.CurrentClass = nil
+		}
+		v.CheckFunction(method, v.FunctionType(method))
+	}
+	v.CurrentClass = currentClassBackup
+	return nil
+}
+
+func (v *TypeChecker) VisitFuncDeclaration(node *ast.FuncDeclaration) interface{} {
+	funcType := v.FunctionType(node)
+	v.Define(node.Name.Lexeme, funcType)
+	v.CheckFunction(node, funcType)
+	return nil
+}
+
+func (v *TypeChecker) CheckFunction(node *ast.FuncDeclaration, funcType *LoxType) {
+	v.EnterScope()
+	for i, param := range node.Params {
+		v.Define(param.Lexeme, funcType.Params[i])
+	}
+	v.ReturnTypes = append(v.ReturnTypes, funcType.Return)
+	node.Body.Accept(v)
+	v.ReturnTypes = v.ReturnTypes[:len(v.ReturnTypes)-1]
+	v.ExitScope()
+}
+
+func (v *TypeChecker) VisitReturnStatement(node *ast.ReturnStatement) interface{} {
+	valueType := NilType
+	if node.Expr != nil {
+		valueType = v.Check(node.Expr)
+	}
+	if len(v.ReturnTypes) == 0 {
+		return nil
+	}
+	expected := v.ReturnTypes[len(v.ReturnTypes)-1]
+	if !expected.AssignableFrom(valueType) {
+		v.Error(node.Keyword, fmt.Sprintf(utils.INVALID_RETURN_TYPE, expected, valueType))
+	}
+	return nil
+}
+
+func (v *TypeChecker) VisitVarDeclaration(node *ast.VarDeclaration) interface{} {
+	declared := v.ResolveAnnotation(node.Type)
+	if node.Expr != nil {
+		valueType := v.Check(node.Expr)
+		if !declared.AssignableFrom(valueType) {
+			v.Error(node.Name, fmt.Sprintf(utils.TYPE_MISMATCH, declared, valueType))
+		}
+	}
+	v.Define(node.Name.Lexeme, declared)
+	return nil
+}
+
+func (v *TypeChecker) VisitAssignment(node *ast.Assignment) interface{} {
+	valueType := v.Check(node.Expr)
+	declared := v.Lookup(node.Name.Lexeme)
+	if node.Operator != nil {
+		valueType = v.BinaryType(*node.Operator, declared, valueType)
+	}
+	if !declared.AssignableFrom(valueType) {
+		v.Error(node.Name, fmt.Sprintf(utils.TYPE_MISMATCH, declared, valueType))
+	}
+	return valueType
+}
+
+func (v *TypeChecker) VisitVariable(node *ast.Variable) interface{} {
+	return v.Lookup(node.Name.Lexeme)
+}
+
+func (v *TypeChecker) VisitBlockStatement(node *ast.BlockStatement) interface{} {
+	v.EnterScope()
+	for _, statement := range node.Statements {
+		statement.Accept(v)
+	}
+	v.ExitScope()
+	return nil
+}
+
+func (v *TypeChecker) VisitExprStatement(node *ast.ExprStatement) interface{} {
+	v.Check(node.Expr)
+	return nil
+}
+
+func (v *TypeChecker) VisitPrintStatement(node *ast.PrintStatement) interface{} {
+	v.Check(node.Node)
+	return nil
+}
+
+func (v *TypeChecker) VisitIfStatement(node *ast.IfStatement) interface{} {
+	v.Check(node.Expr)
+	node.Then.Accept(v)
+	if node.Else != nil {
+		node.Else.Accept(v)
+	}
+	return nil
+}
+
+func (v *TypeChecker) VisitWhileStatement(node *ast.WhileStatement) interface{} {
+	v.Check(node.Expr)
+	node.Then.Accept(v)
+	return nil
+}
+
+func (v *TypeChecker) VisitTryStatement(node *ast.TryStatement) interface{} {
+	node.Body.Accept(v)
+	v.EnterScope()
+	v.Define(node.Name.Lexeme, StringType)
+	node.Catch.Accept(v)
+	v.ExitScope()
+	return nil
+}
+
+func (v *TypeChecker) VisitLiteralExpr(node *ast.LiteralExpr) interface{} {
+	switch node.Value.(type) {
+	case nil:
+		return NilType
+	case float64, int64:
+		return NumberType
+	case string:
+		return StringType
+	case bool:
+		return BoolType
+	default:
+		return AnyType
+	}
+}
+
+func (v *TypeChecker) VisitGroupExpr(node *ast.GroupExpr) interface{} {
+	return v.Check(node.Expr)
+}
+
+// VisitInterpolationExpr checks the interpolated expressions, any value can
+// be interpolated.
+func (v *TypeChecker) VisitInterpolationExpr(node *ast.InterpolationExpr) interface{} {
+	for _, part := range node.Parts {
+		v.Check(part)
+	}
+	return StringType
+}
+
+func (v *TypeChecker) VisitUnaryExpr(node *ast.UnaryExpr) interface{} {
+	rightType := v.Check(node.Right)
+	switch node.Operator.Type {
+	case lexer.MINUS, lexer.TILDE:
+		if !NumberType.AssignableFrom(rightType) {
+			v.Error(node.Operator, fmt.Sprintf(utils.INVALID_OPERAND_TYPE, node.Operator.Lexeme, NumberType, rightType))
+		}
+		return NumberType
+	case lexer.BANG:
+		return BoolType
+	default:
+		return AnyType
+	}
+}
+
+func (v *TypeChecker) VisitBinaryExpr(node *ast.BinaryExpr) interface{} {
+	leftType := v.Check(node.Left)
+	rightType := v.Check(node.Right)
+	return v.BinaryType(node.Operator, leftType, rightType)
+}
+
+// BinaryType is the type of a binary operation, for binary expressions and
+// compound assignments.
+func (v *TypeChecker) BinaryType(operator lexer.Token, leftType *LoxType, rightType *LoxType) *LoxType {
+	switch operator.Type {
+	case lexer.PLUS:
+		if leftType.Kind == TypeAny || rightType.Kind == TypeAny {
+			if leftType.Kind == TypeString || rightType.Kind == TypeString {
+				return StringType
+			}
+			return AnyType
+		}
+		if leftType.Kind == TypeNumber && rightType.Kind == TypeNumber {
+			return NumberType
+		}
+		if leftType.Kind == TypeString && rightType.Kind == TypeString {
+			return StringType
+		}
+		v.Error(operator, fmt.Sprintf(utils.INVALID_OPERAND_TYPES, operator.Lexeme, "two numbers or two strings", leftType, rightType))
+		return AnyType
+	case lexer.MINUS, lexer.STAR, lexer.SLASH, lexer.PERCENT, lexer.TILDE_SLASH, lexer.STAR_STAR,
+		lexer.AMPERSAND, lexer.PIPE, lexer.CARET, lexer.LESS_LESS, lexer.GREATER_GREATER:
+		v.CheckNumberOperands(operator, leftType, rightType)
+		return NumberType
+	case lexer.GREATER, lexer.GREATER_EQUAL, lexer.LESS, lexer.LESS_EQUAL:
+		v.CheckNumberOperands(operator, leftType, rightType)
+		return BoolType
+	case lexer.BANG_EQUAL, lexer.EQUAL_EQUAL:
+		return BoolType
+	default:
+		return AnyType
+	}
+}
+
+func (v *TypeChecker) CheckNumberOperands(token lexer.Token, leftType *LoxType, rightType *LoxType) {
+	if !NumberType.AssignableFrom(leftType) || !NumberType.AssignableFrom(rightType) {
+		v.Error(token, fmt.Sprintf(utils.INVALID_OPERAND_TYPES, token.Lexeme, "numbers", leftType, rightType))
+	}
+}
+
+func (v *TypeChecker) VisitLogicalExpr(node *ast.LogicalExpr) interface{} {
+	leftType := v.Check(node.Left)
+	rightType := v.Check(node.Right)
+	if node.Operator.Type == lexer.QUESTION_QUESTION && leftType.Kind == TypeNil {
+		return rightType
+	}
+	return v.CommonType(leftType, rightType)
+}
+
+func (v *TypeChecker) VisitIndexExpr(node *ast.IndexExpr) interface{} {
+	objectType := v.Check(node.Expr)
+	indexType := v.Check(node.Index)
+	// Only maps, which have no static type, are indexed by strings
+	if !NumberType.AssignableFrom(indexType) && (objectType.Kind == TypeString || !StringType.AssignableFrom(indexType)) {
+		v.Error(node.Bracket, fmt.Sprintf(utils.INVALID_OPERAND_TYPE, "[]", NumberType, indexType))
+	}
+	if objectType.Kind == TypeString {
+		return StringType
+	}
+	return AnyType
+}
+
+func (v *TypeChecker) VisitConditionalExpr(node *ast.ConditionalExpr) interface{} {
+	v.Check(node.Condition)
+	return v.CommonType(v.Check(node.Then), v.Check(node.Else))
+}
+
+// CommonType is the type of an expression that is one of two types.
+func (v *TypeChecker) CommonType(a *LoxType, b *LoxType) *LoxType {
+	if a.Kind != TypeAny && a.Kind == b.Kind && a.Class == b.Class {
+		return a
+	}
+	return AnyType
+}
+
+func (v *TypeChecker) VisitCallExpr(node *ast.CallExpr) interface{} {
+	calleeType := v.Check(node.Callee)
+	var argTypes []*LoxType
+	for _, arg := range node.Arguments {
+		argTypes = append(argTypes, v.Check(arg))
+	}
+
+	switch calleeType.Kind {
+	case TypeFunction:
+		v.CheckArguments(node, calleeType, argTypes)
+		if calleeType.Return == nil {
+			return AnyType
+		}
+		return calleeType.Return
+	case TypeClass:
+		if initializer := calleeType.Class.FindMethod("init"); initializer != nil {
+			v.CheckArguments(node, initializer, argTypes)
+		}
+		return &LoxType{Kind: TypeInstance, Class: calleeType.Class}
+	case TypeAny:
+		return AnyType
+	default:
+		v.Error(node.Paren, fmt.Sprintf(utils.NOT_CALLABLE_TYPE, calleeType))
+		return AnyType
+	}
+}
+
+func (v *TypeChecker) CheckArguments(node *ast.CallExpr, funcType *LoxType, argTypes []*LoxType) {
+	if funcType.Params == nil {
+		return
+	}
+	if len(funcType.Params) != len(argTypes) {
+		v.Error(node.Paren, strings.TrimSpace(fmt.Sprintf(utils.MISMATCH_CALL_PARAMS_LENGTH, len(funcType.Params), len(argTypes))))
+		return
+	}
+	for i, param := range funcType.Params {
+		if !param.AssignableFrom(argTypes[i]) {
+			v.Error(node.Paren, fmt.Sprintf(utils.INVALID_ARGUMENT_TYPE, i+1, funcType, param, argTypes[i]))
+		}
+	}
+}
+
+func (v *TypeChecker) VisitTraitDeclaration(node *ast.TraitDeclaration) interface{} {
+	methods := make(map[string]*LoxType)
+	for _, method := range node.Methods {
+		methods[method.Name.Lexeme] = v.FunctionType(method)
+	}
+	v.Traits[node.Name.Lexeme] = methods
+	v.Define(node.Name.Lexeme, AnyType)
+
+	// this has no static type in a trait, it is an instance of any class
+	// mixing the trait in
+	currentClassBackup := v.CurrentClass
+	v.CurrentClass = nil
+	for _, method := range node.Methods {
+		v.CheckFunction(method, methods[method.Name.Lexeme])
+	}
+	v.CurrentClass = currentClassBackup
+	return nil
+}
+
+func (v *TypeChecker) VisitGetExpr(node *ast.GetExpr) interface{} {
+	objectType := v.Check(node.Expr)
+	if objectType.Kind == TypeClass {
+		if methodType := objectType.Class.FindClassMethod(node.Name.Lexeme); methodType != nil {
+			return methodType
+		}
+		return AnyType
+	}
+	if objectType.Kind != TypeInstance {
+		return AnyType
+	}
+	if fieldType := objectType.Class.FindField(node.Name.Lexeme); fieldType != nil {
+		return fieldType
+	}
+	if methodType := objectType.Class.FindMethod(node.Name.Lexeme); methodType != nil {
+		return methodType
+	}
+	return AnyType
+}
+
+func (v *TypeChecker) VisitSetExpr(node *ast.SetExpr) interface{} {
+	objectType := v.Check(node.Expr)
+	valueType := v.Check(node.Value)
+	var fieldType *LoxType
+	if objectType.Kind == TypeInstance {
+		fieldType = objectType.Class.FindField(node.Name.Lexeme)
+	}
+	if node.Operator != nil {
+		currentType := AnyType
+		if fieldType != nil {
+			currentType = fieldType
+		}
+		valueType = v.BinaryType(*node.Operator, currentType, valueType)
+	}
+	if fieldType != nil && !fieldType.AssignableFrom(valueType) {
+		v.Error(node.Name, fmt.Sprintf(utils.INVALID_FIELD_TYPE, node.Name.Lexeme, fieldType, valueType))
+	}
+	return valueType
+}
+
+func (v *TypeChecker) VisitThisExpr(node *ast.ThisExpr) interface{} {
+	if v.CurrentClass == nil {
+		return AnyType
+	}
+	return &LoxType{Kind: TypeInstance, Class: v.CurrentClass}
+}
+
+func (v *TypeChecker) VisitSuperExpr(node *ast.SuperExpr) interface{} {
+	if v.CurrentClass == nil || v.CurrentClass.SuperClass == nil {
+		return AnyType
+	}
+	if methodType := v.CurrentClass.SuperClass.FindMethod(node.Method.Lexeme); methodType != nil {
+		return methodType
+	}
+	return AnyType
+}
@@ -0,0 +1,110 @@
+package visitor_test
+
+import (
+	"testing"
+
+	"github.com/jameslahm/glox/ast"
+	"github.com/jameslahm/glox/lexer"
+	"github.com/jameslahm/glox/visitor"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func typeCheck(source string) []error {
+	lex := lexer.NewLexer(source)
+	lex.Lex()
+	parser := ast.NewParser(lex.Tokens)
+	node := parser.Parse()
+	checker := visitor.NewTypeChecker()
+	node.Accept(checker)
+	return checker.Errors
+}
+
+func TestTypeCheckUntypedCode(t *testing.T) {
+	errors := typeCheck(`
+var a = 1;
+a = "now a string";
+fun add(x, y) { return x + y; }
+print add(1, 2) + add("a", "b");
+`)
+	assert.Equal(t, len(errors), 0)
+}
+
+func TestTypeCheckAnnotations(t *testing.T) {
+	errors := typeCheck(`
+var n: number = "one";
+fun greet(name: string): string { return "hi " + name; }
+greet(1);
+fun broken(): bool { return 1; }
+`)
+	assert.Equal(t, len(errors), 3)
+	assert.Equal(t, errors[0].Error(), "[line 2:5] Type error: Type mismatch: expected number but got string")
+	assert.Equal(t, errors[1].Error(), "[line 4:8] Type error: Argument 1 of fun(string): string: expected string but got number")
+	assert.Equal(t, errors[2].Error(), "[line 5:22] Type error: Return type mismatch: expected bool but got number")
+}
+
+func TestTypeCheckArithmetic(t *testing.T) {
+	errors := typeCheck(`
+print 1 - "a";
+print "a" + "b";
+print 1 + true;
+`)
+	assert.Equal(t, len(errors), 2)
+}
+
+func TestTypeCheckClassFields(t *testing.T) {
+	errors := typeCheck(`
+class Point {
+  x: number;
+  init(x: number) { this.x = x; }
+}
+class Point3 < Point {}
+var p: Point = Point3(1);
+p.x = "oops";
+var n: number = p.x;
+var q: Missing;
+`)
+	assert.Equal(t, len(errors), 2)
+	assert.Equal(t, errors[0].Error(), "[line 8:3] Type error: Field x: expected number but got string")
+	assert.Equal(t, errors[1].Error(), "[line 10:8] Type error: Unknown type Missing")
+}
+
+func TestTypeCheckClassMembers(t *testing.T) {
+	errors := typeCheck(`
+class Temperature {
+  init() { this.celsius = 0; }
+  fahrenheit { return this.celsius * 9 / 5 + 32; }
+  set fahrenheit(value: number) { this.celsius = (value - 32) * 5 / 9; }
+  class freezing(): Temperature { return Temperature(); }
+}
+var t: Temperature = Temperature.freezing();
+t.fahrenheit = "hot";
+var s: string = Temperature.freezing();
+`)
+	assert.Equal(t, len(errors), 2)
+	assert.Equal(t, errors[0].Error(), "[line 9:3] Type error: Field fahrenheit: expected number but got string")
+	assert.Equal(t, errors[1].Error(), "[line 10:5] Type error: Type mismatch: expected string but got Temperature")
+}
+
+func TestTypeCheckFieldInitializers(t *testing.T) {
+	errors := typeCheck(`
+class Counter {
+  count: number = 0;
+  const label: string = 1;
+  #step = 1;
+}
+`)
+	assert.Equal(t, len(errors), 1)
+	assert.Equal(t, errors[0].Error(), "[line 4:9] Type error: Field label: expected string but got number")
+}
+
+func TestTypeCheckTraits(t *testing.T) {
+	errors := typeCheck(`
+trait Named {
+  name(): string { return "named"; }
+}
+class Item with Named {}
+var n: number = Item().name();
+`)
+	assert.Equal(t, len(errors), 1)
+	assert.Equal(t, errors[0].Error(), "[line 6:5] Type error: Type mismatch: expected number but got string")
+}